@@ -1,17 +1,11 @@
 package main
 
 import (
-	"fmt"
+	"os"
 
-	"mxshs/crawler/src/parser"
+	"mxshs/crawler/src/cli"
 )
 
 func main() {
-    err := parser.Parse("https://leon.ru/bets/esports/1970324836975012-dota2")
-    if err != nil {
-        panic(err)
-    }
-
-    fmt.Println("[INFO] Successfully finished parsing")
+    os.Exit(cli.Run(os.Args[1:]))
 }
-
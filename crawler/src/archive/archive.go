@@ -0,0 +1,417 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of archived pages
+const (
+    Listing = "listing"
+    Match = "match"
+)
+
+// Entry describes one capture of a page. The HTML itself is stored once per
+// distinct content under Hash, so a page that did not change between crawls
+// only adds an entry
+type Entry struct {
+    // Hash is the sha256 of the uncompressed HTML
+    Hash string `json:"hash"`
+    Kind string `json:"kind"`
+    Source string `json:"source"`
+    URL string `json:"url"`
+    RunID int64 `json:"run_id,omitempty"`
+    // ParserVersion of the parser that captured the page, see
+    // core.Registration
+    ParserVersion string `json:"parser_version"`
+    CapturedAt time.Time `json:"captured_at"`
+    // Size of the uncompressed HTML in bytes
+    Size int `json:"size"`
+}
+
+// Options of an archive
+type Options struct {
+    // MaxAge deletes entries captured longer ago, 0 keeps them
+    MaxAge time.Duration
+    // MaxBytes of compressed pages, the oldest days are deleted first. 0
+    // means no limit
+    MaxBytes int64
+}
+
+// Archive keeps the HTML of crawled pages on the local filesystem:
+//
+//   objects/ab/abcdef....html.gz    gzipped HTML named by its sha256
+//   index/2006-01-02.jsonl          entries captured on that day (UTC)
+//
+// Several processes may write to the same archive, blobs are renamed into
+// place and entries are appended a line at a time
+type Archive struct {
+    dir string
+    opts Options
+
+    mu sync.Mutex
+}
+
+func GetArchive(dir string, opts Options) (*Archive, error) {
+    for _, sub := range []string{"objects", "index"} {
+        err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+        if err != nil {
+            return nil, fmt.Errorf("[ERROR] Could not create archive in %s: %s", dir, err.Error())
+        }
+    }
+
+    return &Archive{dir: dir, opts: opts}, nil
+}
+
+// Put stores html unless the same content is archived already and records
+// the entry, e.CapturedAt defaults to now
+func (a *Archive) Put(e Entry, html string) (Entry, error) {
+    sum := sha256.Sum256([]byte(html))
+    e.Hash = hex.EncodeToString(sum[:])
+    e.Size = len(html)
+    if e.CapturedAt.IsZero() {
+        e.CapturedAt = time.Now()
+    }
+    e.CapturedAt = e.CapturedAt.UTC()
+
+    err := a.writeObject(e.Hash, html)
+    if err != nil {
+        return e, err
+    }
+
+    line, err := json.Marshal(e)
+    if err != nil {
+        return e, err
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    f, err := os.OpenFile(a.indexPath(e.CapturedAt), os.O_CREATE | os.O_APPEND | os.O_WRONLY, 0o644)
+    if err != nil {
+        return e, err
+    }
+    defer f.Close()
+
+    _, err = f.Write(append(line, '\n'))
+
+    return e, err
+}
+
+func (a *Archive) writeObject(hash string, html string) error {
+    path := a.objectPath(hash)
+
+    // a page archived before is touched instead, so a prune running
+    // meanwhile takes it for fresh and keeps it until its entry is written
+    now := time.Now()
+
+    err := os.Chtimes(path, now, now)
+    if err == nil {
+        return nil
+    }
+    if !errors.Is(err, fs.ErrNotExist) {
+        return err
+    }
+
+    err = os.MkdirAll(filepath.Dir(path), 0o755)
+    if err != nil {
+        return err
+    }
+
+    var buf bytes.Buffer
+    zw := gzip.NewWriter(&buf)
+
+    _, err = io.WriteString(zw, html)
+    if err != nil {
+        return err
+    }
+
+    err = zw.Close()
+    if err != nil {
+        return err
+    }
+
+    // written aside and renamed, so readers never see half a page
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    _, err = tmp.Write(buf.Bytes())
+    if cerr := tmp.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        return err
+    }
+
+    return os.Rename(tmp.Name(), path)
+}
+
+// Read returns the HTML stored under hash
+func (a *Archive) Read(hash string) (string, error) {
+    if len(hash) < 2 || strings.ContainsAny(hash, `/\.`) {
+        return "", fmt.Errorf("[ERROR] Bad page hash %q", hash)
+    }
+
+    f, err := os.Open(a.objectPath(hash))
+    if errors.Is(err, fs.ErrNotExist) {
+        return "", fmt.Errorf("[ERROR] No archived page %s", hash)
+    }
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+
+    zr, err := gzip.NewReader(f)
+    if err != nil {
+        return "", err
+    }
+    defer zr.Close()
+
+    raw, err := io.ReadAll(zr)
+    if err != nil {
+        return "", err
+    }
+
+    return string(raw), nil
+}
+
+// Filter of Entries, zero fields match everything
+type Filter struct {
+    Source string
+    Kind string
+    URL string
+    Since time.Time
+}
+
+func (f Filter) match(e Entry) bool {
+    return (len(f.Source) == 0 || e.Source == f.Source) &&
+        (len(f.Kind) == 0 || e.Kind == f.Kind) &&
+        (len(f.URL) == 0 || e.URL == f.URL) &&
+        !e.CapturedAt.Before(f.Since)
+}
+
+// Entries lists the archived captures matching f, oldest first
+func (a *Archive) Entries(f Filter) ([]Entry, error) {
+    days, err := a.days()
+    if err != nil {
+        return nil, err
+    }
+
+    var res []Entry
+
+    for _, day := range days {
+        // whole days before Since are skipped without reading them
+        if !f.Since.IsZero() && day.AddDate(0, 0, 1).Before(f.Since) {
+            continue
+        }
+
+        entries, err := a.readDay(day)
+        if err != nil {
+            return nil, err
+        }
+
+        for _, e := range entries {
+            if f.match(e) {
+                res = append(res, e)
+            }
+        }
+    }
+
+    sort.SliceStable(res, func(i, j int) bool {
+        return res[i].CapturedAt.Before(res[j].CapturedAt)
+    })
+
+    return res, nil
+}
+
+// PruneResult counts what Prune deleted
+type PruneResult struct {
+    Days int
+    Objects int
+    Bytes int64
+}
+
+func (r PruneResult) String() string {
+    return fmt.Sprintf("%d days of entries, %d pages (%d KB)", r.Days, r.Objects, r.Bytes / 1024)
+}
+
+// Prune applies the retention limits at now: days of entries older than
+// MaxAge are deleted, then the oldest days until the pages still referenced
+// fit in MaxBytes (the current day is always kept), then every page no entry
+// refers to
+func (a *Archive) Prune(now time.Time) (PruneResult, error) {
+    var res PruneResult
+
+    days, err := a.days()
+    if err != nil {
+        return res, err
+    }
+
+    today := now.UTC().Truncate(24 * time.Hour)
+
+    for len(days) > 0 && a.opts.MaxAge > 0 && days[0].AddDate(0, 0, 1).Before(now.Add(-a.opts.MaxAge)) {
+        err := a.dropDay(days[0])
+        if err != nil {
+            return res, err
+        }
+
+        days = days[1:]
+        res.Days++
+    }
+
+    // pages written by a crawl running right now may not have their entry
+    // yet, they are left for the next prune
+    fresh := now.Add(-time.Hour)
+
+    for {
+        refs := map[string]bool{}
+
+        for _, day := range days {
+            entries, err := a.readDay(day)
+            if err != nil {
+                return res, err
+            }
+
+            for _, e := range entries {
+                refs[e.Hash] = true
+            }
+        }
+
+        kept, err := a.sweep(refs, fresh, &res)
+        if err != nil {
+            return res, err
+        }
+
+        if a.opts.MaxBytes <= 0 || kept <= a.opts.MaxBytes || len(days) == 0 || !days[0].Before(today) {
+            return res, nil
+        }
+
+        err = a.dropDay(days[0])
+        if err != nil {
+            return res, err
+        }
+
+        days = days[1:]
+        res.Days++
+    }
+}
+
+// sweep deletes the pages not in refs and returns the size of the rest
+func (a *Archive) sweep(refs map[string]bool, fresh time.Time, res *PruneResult) (int64, error) {
+    var kept int64
+
+    err := filepath.WalkDir(filepath.Join(a.dir, "objects"), func(path string, d fs.DirEntry, err error) error {
+        if err != nil || d.IsDir() {
+            return err
+        }
+
+        info, err := d.Info()
+        if errors.Is(err, fs.ErrNotExist) {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+
+        hash := strings.TrimSuffix(d.Name(), ".html.gz")
+        if refs[hash] || info.ModTime().After(fresh) {
+            kept += info.Size()
+            return nil
+        }
+
+        err = os.Remove(path)
+        if err != nil && !errors.Is(err, fs.ErrNotExist) {
+            return err
+        }
+
+        res.Objects++
+        res.Bytes += info.Size()
+
+        return nil
+    })
+
+    return kept, err
+}
+
+func (a *Archive) days() ([]time.Time, error) {
+    files, err := os.ReadDir(filepath.Join(a.dir, "index"))
+    if err != nil {
+        return nil, err
+    }
+
+    var days []time.Time
+
+    for _, f := range files {
+        day, err := time.Parse("2006-01-02", strings.TrimSuffix(f.Name(), ".jsonl"))
+        if err == nil && !f.IsDir() {
+            days = append(days, day)
+        }
+    }
+
+    sort.Slice(days, func(i, j int) bool {
+        return days[i].Before(days[j])
+    })
+
+    return days, nil
+}
+
+func (a *Archive) readDay(day time.Time) ([]Entry, error) {
+    f, err := os.Open(a.indexPath(day))
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    var res []Entry
+
+    scanner := bufio.NewScanner(f)
+    scanner.Buffer(make([]byte, 64 * 1024), 1024 * 1024)
+
+    for scanner.Scan() {
+        var e Entry
+
+        // a line cut short by a crash is skipped, the rest of the day is fine
+        if json.Unmarshal(scanner.Bytes(), &e) == nil {
+            res = append(res, e)
+        }
+    }
+
+    return res, scanner.Err()
+}
+
+func (a *Archive) dropDay(day time.Time) error {
+    err := os.Remove(a.indexPath(day))
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil
+    }
+
+    return err
+}
+
+func (a *Archive) indexPath(t time.Time) string {
+    return filepath.Join(a.dir, "index", t.UTC().Format("2006-01-02") + ".jsonl")
+}
+
+func (a *Archive) objectPath(hash string) string {
+    return filepath.Join(a.dir, "objects", hash[:2], hash + ".html.gz")
+}
@@ -0,0 +1,187 @@
+package archive
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var captured = time.Date(2026, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+func testArchive(t *testing.T, opts Options) *Archive {
+    t.Helper()
+
+    a, err := GetArchive(t.TempDir(), opts)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return a
+}
+
+func put(t *testing.T, a *Archive, url string, html string, at time.Time) Entry {
+    t.Helper()
+
+    e, err := a.Put(Entry{Kind: Match, Source: "leon", URL: url, CapturedAt: at}, html)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return e
+}
+
+// age makes the stored page of e look written at t
+func age(t *testing.T, a *Archive, e Entry, at time.Time) {
+    t.Helper()
+
+    err := os.Chtimes(a.objectPath(e.Hash), at, at)
+    if err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestPutRead(t *testing.T) {
+    a := testArchive(t, Options{})
+
+    first := put(t, a, "/1", "<div>Spirit - OG</div>", captured)
+    second := put(t, a, "/1", "<div>Spirit - OG</div>", captured.Add(time.Minute))
+    other := put(t, a, "/2", "<div>Tundra - OG</div>", captured.Add(24 * time.Hour))
+
+    if first.Hash != second.Hash || first.Hash == other.Hash {
+        t.Errorf("hashes %s %s %s, want the same content stored once", first.Hash, second.Hash, other.Hash)
+    }
+
+    html, err := a.Read(first.Hash)
+    if err != nil || html != "<div>Spirit - OG</div>" {
+        t.Errorf("Read = %q, %v", html, err)
+    }
+
+    for _, hash := range []string{"", "../index", strings.Repeat("0", 64)} {
+        if _, err := a.Read(hash); err == nil {
+            t.Errorf("Read(%q) did not fail", hash)
+        }
+    }
+
+    cases := []struct {
+        name string
+        filter Filter
+        want int
+    }{
+        {"all", Filter{}, 3},
+        {"url", Filter{URL: "/1"}, 2},
+        {"since", Filter{Since: captured.Add(time.Hour)}, 1},
+        {"other source", Filter{Source: "ggbet"}, 0},
+        {"kind", Filter{Kind: Listing}, 0},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            entries, err := a.Entries(c.filter)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if len(entries) != c.want {
+                t.Errorf("got %d entries, want %d", len(entries), c.want)
+            }
+
+            for i := 1; i < len(entries); i++ {
+                if entries[i].CapturedAt.Before(entries[i - 1].CapturedAt) {
+                    t.Errorf("entries not ordered by capture: %+v", entries)
+                }
+            }
+        })
+    }
+}
+
+func TestPruneMaxAge(t *testing.T) {
+    a := testArchive(t, Options{MaxAge: 48 * time.Hour})
+    now := captured.Add(10 * 24 * time.Hour)
+
+    old := put(t, a, "/1", "old", captured)
+    shared := put(t, a, "/2", "shared", captured)
+    put(t, a, "/2", "shared", now)
+    age(t, a, old, captured)
+    age(t, a, shared, captured)
+
+    res, err := a.Prune(now)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if res.Days != 1 || res.Objects != 1 {
+        t.Errorf("pruned %s, want the old day and its page", res)
+    }
+
+    if _, err := a.Read(old.Hash); err == nil {
+        t.Error("page of the pruned day kept")
+    }
+
+    if _, err := a.Read(shared.Hash); err != nil {
+        t.Errorf("page still referenced deleted: %s", err)
+    }
+}
+
+func TestPruneMaxBytes(t *testing.T) {
+    a := testArchive(t, Options{MaxBytes: 1})
+    now := captured.Add(2 * 24 * time.Hour)
+
+    for i, at := range []time.Time{captured, captured.Add(24 * time.Hour), now} {
+        e := put(t, a, "/1", strings.Repeat("x", i + 1), at)
+        age(t, a, e, captured)
+    }
+
+    res, err := a.Prune(now)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if res.Days != 2 || res.Objects != 2 {
+        t.Errorf("pruned %s, want all but the current day", res)
+    }
+
+    entries, err := a.Entries(Filter{})
+    if err != nil || len(entries) != 1 || !entries[0].CapturedAt.Equal(now) {
+        t.Errorf("entries left %+v, %v, want the current day", entries, err)
+    }
+}
+
+// a page stored again while a prune runs is touched before its entry is
+// written, the prune must not delete it in between
+func TestPutKeepsPageFromSweep(t *testing.T) {
+    a := testArchive(t, Options{})
+    now := time.Now()
+
+    e := put(t, a, "/1", "<div>Spirit - OG</div>", now)
+    age(t, a, e, now.Add(-48 * time.Hour))
+
+    // the object part of a second Put, its entry is not written yet
+    err := a.writeObject(e.Hash, "<div>Spirit - OG</div>")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var res PruneResult
+
+    _, err = a.sweep(map[string]bool{}, now.Add(-time.Hour), &res)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := a.Read(e.Hash); err != nil {
+        t.Errorf("page deleted by the sweep: %s", err)
+    }
+
+    // nothing touched it this time
+    age(t, a, e, now.Add(-48 * time.Hour))
+
+    _, err = a.sweep(map[string]bool{}, now.Add(-time.Hour), &res)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := a.Read(e.Hash); err == nil {
+        t.Error("unreferenced page kept")
+    }
+}
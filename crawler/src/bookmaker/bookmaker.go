@@ -0,0 +1,151 @@
+package bookmaker
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Bookmaker is a site the crawler can collect odds from. Code is what gets
+// stored as the source of every game and snapshot
+type Bookmaker struct {
+    Code string
+    Name string
+    BaseURL string
+    Hosts []string
+}
+
+var (
+    Leon = Bookmaker{
+        Code: "leon",
+        Name: "Leon",
+        BaseURL: "https://leon.ru",
+        Hosts: []string{"leon.ru"},
+    }
+    LigaStavok = Bookmaker{
+        Code: "ligastavok",
+        Name: "Liga Stavok",
+        BaseURL: "https://www.ligastavok.ru",
+        Hosts: []string{"ligastavok.ru"},
+    }
+    D2Lounge = Bookmaker{
+        Code: "d2lounge",
+        Name: "Dota 2 Lounge",
+        BaseURL: "https://dota2lounge.com",
+        Hosts: []string{"dota2lounge.com"},
+    }
+    GGBet = Bookmaker{
+        Code: "ggbet",
+        Name: "GG.BET",
+        BaseURL: "https://the-ggbet.com",
+        Hosts: []string{"the-ggbet.com"},
+    }
+)
+
+var (
+    // mu guards the bookmaker variables and others against Define and
+    // Override
+    mu sync.RWMutex
+    // others are the bookmakers only known from site definitions
+    others []*Bookmaker
+)
+
+func All() []Bookmaker {
+    mu.RLock()
+    defer mu.RUnlock()
+
+    res := []Bookmaker{Leon, LigaStavok, D2Lounge, GGBet}
+    for _, b := range others {
+        res = append(res, *b)
+    }
+
+    return res
+}
+
+// find the bookmaker variable of code, mu must be held
+func find(code string) *Bookmaker {
+    for _, b := range append([]*Bookmaker{&Leon, &LigaStavok, &D2Lounge, &GGBet}, others...) {
+        if b.Code == code {
+            return b
+        }
+    }
+
+    return nil
+}
+
+// Define adds a bookmaker or replaces the name, base url and hosts of the
+// one with the same code, for sites defined outside of the code. Meant to
+// be called before crawling starts
+func Define(b Bookmaker) error {
+    if len(b.Code) == 0 {
+        return fmt.Errorf("[ERROR] Bookmaker needs a code")
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+
+    b.BaseURL = strings.TrimSuffix(b.BaseURL, "/")
+
+    if known := find(b.Code); known != nil {
+        *known = b
+        return nil
+    }
+
+    others = append(others, &b)
+
+    return nil
+}
+
+// Override points a bookmaker at another site, e.g. a local fake of it in
+// end to end tests: BaseURL becomes baseURL and its host the only one the
+// bookmaker is recognized by. Meant to be called before crawling starts
+func Override(code string, baseURL string) error {
+    u, err := url.Parse(baseURL)
+    if err != nil || len(u.Hostname()) == 0 {
+        return fmt.Errorf("[ERROR] Base url of %s must be absolute, got %q", code, baseURL)
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+
+    b := find(code)
+    if b == nil {
+        return fmt.Errorf("[ERROR] Unknown bookmaker %q", code)
+    }
+
+    b.BaseURL = strings.TrimSuffix(baseURL, "/")
+    b.Hosts = []string{strings.ToLower(u.Hostname())}
+
+    return nil
+}
+
+func ByCode(code string) (Bookmaker, bool) {
+    for _, b := range All() {
+        if b.Code == code {
+            return b, true
+        }
+    }
+
+    return Bookmaker{}, false
+}
+
+// ByURL finds the bookmaker serving the given page, subdomains included
+func ByURL(rawURL string) (Bookmaker, bool) {
+    u, err := url.Parse(rawURL)
+    if err != nil {
+        return Bookmaker{}, false
+    }
+
+    host := strings.ToLower(u.Hostname())
+
+    for _, b := range All() {
+        for _, h := range b.Hosts {
+            if host == h || strings.HasSuffix(host, "." + h) {
+                return b, true
+            }
+        }
+    }
+
+    return Bookmaker{}, false
+}
@@ -0,0 +1,48 @@
+package bookmaker
+
+import (
+	"testing"
+)
+
+func TestOverrideRestore(t *testing.T) {
+    saved, ok := ByCode("ligastavok")
+    if !ok {
+        t.Fatal("ligastavok is not defined")
+    }
+
+    err := Override("ligastavok", "http://127.0.0.1:8080/ligastavok")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cases := []struct {
+        url string
+        want string
+    }{
+        {"http://127.0.0.1:8080/ligastavok/listing", "ligastavok"},
+        {"https://www.ligastavok.ru/bets", ""},
+    }
+
+    for _, c := range cases {
+        b, _ := ByURL(c.url)
+        if b.Code != c.want {
+            t.Errorf("overridden: ByURL(%q) = %q, want %q", c.url, b.Code, c.want)
+        }
+    }
+
+    err = Define(saved)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    b, _ := ByCode("ligastavok")
+    if b.BaseURL != "https://www.ligastavok.ru" || len(b.Hosts) != 1 || b.Hosts[0] != "ligastavok.ru" {
+        t.Errorf("restored %+v, want the built in bookmaker", b)
+    }
+
+    for _, url := range []string{"https://www.ligastavok.ru/bets", "https://ligastavok.ru/bets"} {
+        if b, _ := ByURL(url); b.Code != "ligastavok" {
+            t.Errorf("restored: ByURL(%q) = %q, want ligastavok", url, b.Code)
+        }
+    }
+}
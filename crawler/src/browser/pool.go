@@ -0,0 +1,321 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Options of a browser pool
+type Options struct {
+    // Size is the number of Chrome processes kept running
+    Size int
+    // Tabs is the number of pages open at once in a single browser
+    Tabs int
+    // MaxPages restarts a browser after it served that many pages, which
+    // keeps memory leaks of long sessions in check. 0 never restarts it
+    MaxPages int
+    // Flags are passed to every Chrome process
+    Flags []chromedp.ExecAllocatorOption
+}
+
+// Flags used when none are set: headless with a desktop sized window, so
+// sites do not fall back to their mobile version
+func DefaultFlags() []chromedp.ExecAllocatorOption {
+    flags := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+
+    return append(
+        flags,
+        chromedp.Flag("force-device-scale-factor", "1"),
+        chromedp.WindowSize(1920, 1080),
+    )
+}
+
+// Pool keeps a few long lived browsers and hands out tabs in them, so a
+// crawl does not start a Chrome process per page. Browsers are started on
+// first use, restarted after MaxPages pages and replaced when they crash
+type Pool struct {
+    opts Options
+    // one slot per tab that may be open at once across all browsers
+    slots chan struct{}
+
+    mu sync.Mutex
+    browsers []*instance
+    closed bool
+}
+
+type instance struct {
+    ctx context.Context
+    cancel context.CancelFunc
+    // closed once Chrome started or failed to, the failure is in err. ctx
+    // and cancel are set when it started
+    ready chan struct{}
+    err error
+    active int
+    pages int
+    // retired browsers take no new tabs and exit when the last one closes
+    retired bool
+}
+
+func GetPool(opts Options) *Pool {
+    if opts.Size <= 0 {
+        opts.Size = 1
+    }
+
+    if opts.Tabs <= 0 {
+        opts.Tabs = 1
+    }
+
+    if len(opts.Flags) == 0 {
+        opts.Flags = DefaultFlags()
+    }
+
+    return &Pool{
+        opts: opts,
+        slots: make(chan struct{}, opts.Size * opts.Tabs),
+    }
+}
+
+var (
+    defaultPool *Pool
+    defaultOnce sync.Once
+)
+
+// Default is a pool of one browser with a few tabs, used by parsers that
+// were not given one
+func Default() *Pool {
+    defaultOnce.Do(func() {
+        defaultPool = GetPool(Options{Size: 1, Tabs: 3, MaxPages: 50})
+    })
+
+    return defaultPool
+}
+
+// Tab opens a new tab, waiting for a free slot when all of them are in use.
+// The tab is closed when ctx is done or the returned function is called,
+// which must always happen
+func (p *Pool) Tab(ctx context.Context) (context.Context, func(), error) {
+    select {
+    case p.slots <- struct{}{}:
+    case <-ctx.Done():
+        return nil, nil, ctx.Err()
+    }
+
+    p.mu.Lock()
+    b, starting, err := p.pick()
+    if err != nil {
+        p.mu.Unlock()
+        <-p.slots
+        return nil, nil, err
+    }
+
+    b.active++
+    b.pages++
+    if p.opts.MaxPages > 0 && b.pages >= p.opts.MaxPages {
+        b.retired = true
+    }
+    p.mu.Unlock()
+
+    // Chrome is launched outside of the lock, tabs of the other browsers
+    // are handed out meanwhile
+    if starting {
+        p.launch(b)
+    }
+
+    select {
+    case <-b.ready:
+        err = b.err
+    case <-ctx.Done():
+        err = ctx.Err()
+    }
+
+    if err != nil {
+        p.mu.Lock()
+        b.active--
+        p.release(b)
+        p.mu.Unlock()
+
+        <-p.slots
+        return nil, nil, err
+    }
+
+    tab, cancel := chromedp.NewContext(b.ctx)
+    // tabs belong to the browser context, cancelling the caller's one only
+    // closes the tab and leaves the browser running for the others
+    stop := context.AfterFunc(ctx, cancel)
+
+    var once sync.Once
+    release := func() {
+        once.Do(func() {
+            stop()
+            cancel()
+
+            p.mu.Lock()
+            b.active--
+            p.release(b)
+            p.mu.Unlock()
+
+            <-p.slots
+        })
+    }
+
+    return tab, release, nil
+}
+
+// Close stops every browser and waits for the Chrome processes to exit,
+// tabs still open are cancelled
+func (p *Pool) Close() {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    p.closed = true
+
+    // browsers still starting are stopped by launch
+    for _, b := range p.browsers {
+        if b.started() {
+            b.cancel()
+        }
+    }
+
+    p.browsers = nil
+}
+
+// browser with the fewest open tabs, adding one while there are less than
+// Size of them. A browser that was added is not started yet, the caller
+// launches it without holding the lock. Must be called with mu held
+func (p *Pool) pick() (*instance, bool, error) {
+    if p.closed {
+        return nil, false, fmt.Errorf("[ERROR] Browser pool is closed\n")
+    }
+
+    // crashed browsers without open tabs are removed, the others once their
+    // last tab closes
+    for _, b := range slices.Clone(p.browsers) {
+        p.release(b)
+    }
+
+    var best *instance
+    running := 0
+
+    for _, b := range p.browsers {
+        if b.retired {
+            continue
+        }
+
+        running++
+
+        if b.active < p.opts.Tabs && (best == nil || b.active < best.active) {
+            best = b
+        }
+    }
+
+    if best != nil && (best.active == 0 || running >= p.opts.Size) {
+        return best, false, nil
+    }
+
+    if running >= p.opts.Size {
+        // can not happen while slots limit the number of open tabs
+        return nil, false, fmt.Errorf("[ERROR] No browser has a free tab\n")
+    }
+
+    // a placeholder counts towards Size while Chrome starts, so concurrent
+    // callers neither start another one nor wait for the lock meanwhile
+    b := &instance{ready: make(chan struct{})}
+    p.browsers = append(p.browsers, b)
+
+    return b, true, nil
+}
+
+// launch starts Chrome for a browser added by pick, a browser that failed
+// to start is removed with its error for the tabs waiting on it
+func (p *Pool) launch(b *instance) {
+    started, err := p.start()
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if err == nil && p.closed {
+        started.cancel()
+        err = fmt.Errorf("[ERROR] Browser pool is closed\n")
+    }
+
+    if err != nil {
+        b.err = err
+        p.remove(b)
+        close(b.ready)
+        return
+    }
+
+    b.ctx = started.ctx
+    b.cancel = started.cancel
+    close(b.ready)
+
+    // every tab waiting for it may have given up already
+    p.release(b)
+}
+
+func (p *Pool) start() (*instance, error) {
+    allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), p.opts.Flags...)
+    ctx, cancel := chromedp.NewContext(allocCtx)
+
+    // running with no actions launches the browser, tabs are then created
+    // inside of it instead of starting a process each
+    err := chromedp.Run(ctx)
+    if err != nil {
+        cancel()
+        allocCancel()
+        return nil, fmt.Errorf("[ERROR] Failed to start browser: %s\n", err.Error())
+    }
+
+    return &instance{
+        ctx: ctx,
+        cancel: func() {
+            cancel()
+            allocCancel()
+        },
+    }, nil
+}
+
+// stops the browser once it is retired or crashed and has no open tabs.
+// Must be called with mu held
+func (p *Pool) release(b *instance) {
+    if !b.started() {
+        return
+    }
+
+    if b.ctx.Err() != nil {
+        // the Chrome process exited, the allocator cancels its context
+        b.retired = true
+    }
+
+    if !b.retired || b.active > 0 {
+        return
+    }
+
+    b.cancel()
+    p.remove(b)
+}
+
+// remove drops b from the browsers. Must be called with mu held
+func (p *Pool) remove(b *instance) {
+    for i, other := range p.browsers {
+        if other == b {
+            p.browsers = append(p.browsers[:i], p.browsers[i + 1:]...)
+            break
+        }
+    }
+}
+
+// started tells whether Chrome of b is running or ran, browsers still
+// starting or that failed to start have no context yet
+func (b *instance) started() bool {
+    select {
+    case <-b.ready:
+        return b.err == nil
+    default:
+        return false
+    }
+}
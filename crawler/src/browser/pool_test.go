@@ -0,0 +1,62 @@
+package browser
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// a browser that fails to start is dropped and its tab slots are freed, so
+// the next tab tries again instead of waiting on it
+func TestTabStartFails(t *testing.T) {
+    p := GetPool(Options{
+        Size: 1,
+        Tabs: 2,
+        Flags: []chromedp.ExecAllocatorOption{chromedp.ExecPath(filepath.Join(t.TempDir(), "chrome"))},
+    })
+    defer p.Close()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+    defer cancel()
+
+    var wg sync.WaitGroup
+
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+
+        go func() {
+            defer wg.Done()
+
+            _, _, err := p.Tab(ctx)
+            if err == nil {
+                t.Error("got a tab of a browser that can not start")
+            }
+        } ()
+    }
+
+    wg.Wait()
+
+    if ctx.Err() != nil {
+        t.Fatal("tabs waited for a browser that failed to start")
+    }
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if len(p.browsers) != 0 || len(p.slots) != 0 {
+        t.Errorf("%d browsers and %d slots left in use", len(p.browsers), len(p.slots))
+    }
+}
+
+func TestTabClosed(t *testing.T) {
+    p := GetPool(Options{})
+    p.Close()
+
+    if _, _, err := p.Tab(context.Background()); err == nil {
+        t.Error("got a tab of a closed pool")
+    }
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mxshs/crawler/src/archive"
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/logs"
+)
+
+// archiveCmd handles `crawler archive [list [flags] | show <hash> | prune]`
+func archiveCmd(ctx context.Context, cfg *config.Config, args []string) error {
+    a, err := openArchive(cfg)
+    if err != nil {
+        return err
+    }
+
+    if a == nil {
+        return fmt.Errorf("[ERROR] The archive is disabled, set archive.dir in the config")
+    }
+
+    cmd := "list"
+    if len(args) > 0 {
+        cmd, args = args[0], args[1:]
+    }
+
+    switch cmd {
+    case "list":
+        fs := flags("archive list")
+        source := fs.String("source", "", "only pages of this bookmaker code")
+        kind := fs.String("kind", "", "listing or match")
+        url := fs.String("url", "", "only captures of this page")
+        since := fs.Duration("since", 24 * time.Hour, "only pages captured within this window, 0 for all")
+
+        err := parse(fs, args)
+        if err != nil {
+            return err
+        }
+
+        f := archive.Filter{Source: *source, Kind: *kind, URL: *url}
+        if *since > 0 {
+            f.Since = time.Now().Add(-*since)
+        }
+
+        entries, err := a.Entries(f)
+        if err != nil {
+            return err
+        }
+
+        for _, e := range entries {
+            fmt.Printf(
+                "%s %s %-10s %-7s v%-3s %8d %s\n",
+                e.CapturedAt.Format(time.RFC3339),
+                e.Hash,
+                e.Source,
+                e.Kind,
+                e.ParserVersion,
+                e.Size,
+                e.URL,
+            )
+        }
+    case "show":
+        if len(args) != 1 {
+            return usagef("[ERROR] Expected the hash of a page, see `crawler archive list`")
+        }
+
+        html, err := a.Read(args[0])
+        if err != nil {
+            return err
+        }
+
+        fmt.Println(html)
+    case "prune":
+        res, err := a.Prune(time.Now())
+        if err != nil {
+            return err
+        }
+
+        fmt.Printf("Deleted %s\n", res)
+    default:
+        return usagef("[ERROR] Unknown archive command: %s", cmd)
+    }
+
+    return nil
+}
+
+// openArchive returns the configured archive, nil when it is disabled
+func openArchive(cfg *config.Config) (*archive.Archive, error) {
+    if len(cfg.Archive.Dir) == 0 {
+        return nil, nil
+    }
+
+    return archive.GetArchive(cfg.Archive.Dir, archive.Options{
+        MaxAge: cfg.Archive.MaxAge,
+        MaxBytes: int64(cfg.Archive.MaxMB) << 20,
+    })
+}
+
+// pruneArchive applies the retention limits after a crawl, failing to does
+// not fail the crawl
+func pruneArchive(a *archive.Archive) {
+    if a == nil {
+        return
+    }
+
+    res, err := a.Prune(time.Now())
+    if err != nil {
+        logs.Errorf("Could not prune the archive: %s", err.Error())
+        return
+    }
+
+    if res.Days > 0 || res.Objects > 0 {
+        logs.Infof("Pruned the archive: %s", res)
+    }
+}
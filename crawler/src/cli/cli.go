@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/core"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+
+	"github.com/joho/godotenv"
+)
+
+// Exit codes of the binary
+const (
+    ExitOK = 0
+    // the command failed
+    ExitError = 1
+    // unknown command, bad flags or arguments
+    ExitUsage = 2
+    // a crawl finished but some of its pages failed
+    ExitPartial = 3
+    // stopped by SIGINT/SIGTERM
+    ExitInterrupted = 130
+)
+
+type command struct {
+    name string
+    usage string
+    summary string
+    run func(ctx context.Context, cfg *config.Config, args []string) error
+}
+
+var commands = []command{
+    {"crawl", "crawl [flags] [url]...", "crawl listing pages and store the odds of every match", crawl},
+    {"sources", "sources", "list supported sites and what their parsers can do", sources},
+    {"migrate", "migrate [up | down [steps] | status]", "apply, revert or show schema migrations", migrate},
+    {"teams", "teams [list | pending | add <team> | approve <alias> [team]]", "review team name aliases", teams},
+    {"export", "export [flags]", "write stored odds snapshots as JSON lines or CSV", export},
+    {"serve", "serve [flags]", "serve crawl runs and snapshots over HTTP", serve},
+    {"daemon", "daemon [flags]", "crawl the sources of the config on their schedules", daemon},
+    {"work", "work [flags]", "crawl the match pages waiting in the job queue", work},
+    {"queue", "queue [status | requeue [source] | purge [--older 24h]]", "show or manage the job queue", queue},
+    {"archive", "archive [list [flags] | show <hash> | prune]", "browse the archived HTML of crawled pages", archiveCmd},
+    {"replay", "replay [flags]", "parse archived pages again and diff them with the stored data", replayCmd},
+    {"inspect", "inspect <match url>", "parse a single match page and print it without storing", inspect},
+    {"config", "config check", "validate the config and print the settings in effect", checkConfig},
+}
+
+// usageError is reported with the usage of the command and ExitUsage
+type usageError struct {
+    msg string
+}
+
+func (e *usageError) Error() string {
+    return e.msg
+}
+
+func usagef(format string, args ...any) error {
+    return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// partialError is returned by crawl when some pages could not be stored
+type partialError struct {
+    failed int
+}
+
+func (e *partialError) Error() string {
+    return fmt.Sprintf("[ERROR] %d pages failed", e.failed)
+}
+
+// Run executes the command line (without the program name) and returns the
+// exit code. `crawler [--config path] [--log-level level] <command> [args]`
+func Run(args []string) int {
+    global := flag.NewFlagSet("crawler", flag.ContinueOnError)
+    global.SetOutput(io.Discard)
+    path := global.String("config", config.DefaultPath, "YAML config file")
+    level := global.String("log-level", "", "debug, info or error (log_level of the config by default)")
+
+    err := global.Parse(args)
+    if err != nil {
+        printUsage(os.Stderr)
+        if errors.Is(err, flag.ErrHelp) {
+            return ExitOK
+        }
+        logs.Err(err)
+        return ExitUsage
+    }
+
+    args = global.Args()
+    if len(args) == 0 {
+        printUsage(os.Stderr)
+        return ExitUsage
+    }
+
+    name := args[0]
+    if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+        // `crawler <url>` is a shorthand for `crawler crawl <url>`
+        name, args = "crawl", append([]string{"crawl"}, args...)
+    }
+
+    if name == "help" || name == "-h" || name == "--help" {
+        printUsage(os.Stdout)
+        return ExitOK
+    }
+
+    var cmd *command
+    for i := range commands {
+        if commands[i].name == name {
+            cmd = &commands[i]
+        }
+    }
+
+    if cmd == nil {
+        logs.Errorf("Unknown command: %s", name)
+        printUsage(os.Stderr)
+        return ExitUsage
+    }
+
+    if isSet(global, "log-level") {
+        l, err := logs.ParseLevel(*level)
+        if err != nil {
+            logs.Err(err)
+            return ExitUsage
+        }
+        logs.SetLevel(l)
+    }
+
+    cfg, err := loadConfig(*path, isSet(global, "config"))
+    if err != nil {
+        logs.Err(err)
+        return ExitUsage
+    }
+
+    if isSet(global, "log-level") {
+        cfg.LogLevel = *level
+    }
+
+    // before base_urls, which may point the sites defined there elsewhere
+    err = core.LoadSites(cfg.Sites...)
+    if err != nil {
+        logs.Err(err)
+        return ExitUsage
+    }
+
+    err = cfg.ApplyBaseURLs()
+    if err != nil {
+        logs.Err(err)
+        return ExitUsage
+    }
+
+    err = cfg.Validate()
+    if err != nil {
+        logs.Err(err)
+        return ExitUsage
+    }
+
+    l, _ := logs.ParseLevel(cfg.LogLevel)
+    logs.SetLevel(l)
+
+    return exec(*cmd, cfg, args[1:])
+}
+
+// loadConfig reads .env into the environment when there is one (variables
+// already set win), then the config file with the environment on top
+func loadConfig(path string, required bool) (*config.Config, error) {
+    err := godotenv.Load(".env")
+    if err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return nil, fmt.Errorf("[ERROR] Could not read .env: %s", err.Error())
+    }
+
+    return config.Load(path, required)
+}
+
+func exec(c command, cfg *config.Config, args []string) int {
+    // the first SIGINT/SIGTERM stops the command gracefully: pages already
+    // loaded are stored and Chrome is shut down. A second one kills the process
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    go func() {
+        <-ctx.Done()
+        stop()
+    } ()
+
+    err := c.run(ctx, cfg, args)
+
+    var usage *usageError
+    var partial *partialError
+
+    switch {
+    case err == nil:
+        return ExitOK
+    case errors.Is(err, flag.ErrHelp):
+        return ExitOK
+    case errors.As(err, &usage):
+        logs.Err(err)
+        fmt.Fprintf(os.Stderr, "usage: crawler %s\n", c.usage)
+        return ExitUsage
+    case errors.Is(err, context.Canceled):
+        logs.Infof("Interrupted")
+        return ExitInterrupted
+    case errors.As(err, &partial):
+        logs.Err(err)
+        return ExitPartial
+    default:
+        logs.Err(err)
+        return ExitError
+    }
+}
+
+func printUsage(w io.Writer) {
+    fmt.Fprintf(w, "usage: crawler [--config crawler.yaml] [--log-level debug|info|error] <command> [args]\n\ncommands:\n")
+
+    for _, c := range commands {
+        fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+    }
+
+    fmt.Fprintf(w, "\nrun `crawler <command> -h` for the flags of a command\n")
+}
+
+// flags returns a flag set for a command that reports errors as usage errors
+func flags(name string) *flag.FlagSet {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.SetOutput(os.Stderr)
+
+    return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+    err := fs.Parse(args)
+    if err == nil || errors.Is(err, flag.ErrHelp) {
+        return err
+    }
+
+    return usagef("%s", err.Error())
+}
+
+func isSet(fs *flag.FlagSet, name string) bool {
+    set := false
+
+    fs.Visit(func(f *flag.Flag) {
+        if f.Name == name {
+            set = true
+        }
+    })
+
+    return set
+}
+
+// open returns the storage selected by sink (db.driver when empty) with its
+// schema up to date
+func open(cfg *config.Config, sink string) (db.Storage, error) {
+    store, err := db.OpenSink(sink, cfg.DB)
+    if err != nil {
+        return nil, err
+    }
+
+    err = store.Migrate()
+    if err != nil {
+        store.Close()
+        return nil, err
+    }
+
+    return store, nil
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/core"
+	"mxshs/crawler/src/logs"
+)
+
+// checkConfig handles `crawler config check`. The config was already loaded
+// and validated before any command runs, so this only prints what is in
+// effect, fails on sources nothing can crawl and warns about schedules
+// slower than the adaptive policy
+func checkConfig(ctx context.Context, cfg *config.Config, args []string) error {
+    if len(args) != 1 || args[0] != "check" {
+        return usagef("[ERROR] Expected `config check`")
+    }
+
+    for _, url := range cfg.StartURLs() {
+        reg, err := core.Lookup(url)
+        if err != nil {
+            return err
+        }
+
+        if !reg.Can(core.MatchList) {
+            return fmt.Errorf("[ERROR] %s parser can not crawl listing pages like %s", reg.Bookmaker.Name, url)
+        }
+    }
+
+    fmt.Print(cfg)
+
+    for _, w := range cfg.Warnings() {
+        logs.Infof("Warning: %s", w)
+    }
+
+    logs.Infof("Config is valid")
+
+    return nil
+}
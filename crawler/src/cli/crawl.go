@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mxshs/crawler/src/browser"
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/parser"
+	"mxshs/crawler/src/retry"
+)
+
+// crawl handles `crawler crawl [--concurrency n] [--sink spec] [url]...`,
+// urls are crawled one after another, each in its own run. Without urls the
+// sources of the config are crawled
+func crawl(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("crawl")
+    concurrency := fs.Int("concurrency", cfg.Crawl.Workers, "match pages loaded at once (crawl.workers)")
+    all := fs.Bool("all", false, "crawl every listed match even when adaptive is enabled")
+    sink := fs.String("sink", cfg.Sink, "where to write: postgres, sqlite[:path], memory or jsonl[:path] (db.driver when empty)")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    urls := fs.Args()
+    if len(urls) == 0 {
+        urls = cfg.StartURLs()
+    }
+
+    if len(urls) == 0 {
+        return usagef("[ERROR] No url to crawl, pass one or list them under sources in the config")
+    }
+
+    if *concurrency < 1 {
+        return usagef("[ERROR] --concurrency must be at least 1")
+    }
+
+    opts := crawlOptions(cfg)
+    opts.Workers = *concurrency
+
+    if *all {
+        opts.Priority = nil
+    }
+
+    opts.Archive, err = openArchive(cfg)
+    if err != nil {
+        return err
+    }
+
+    store, err := open(cfg, *sink)
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    err = crawlURLs(ctx, store, urls, opts)
+    pruneArchive(opts.Archive)
+
+    return err
+}
+
+// crawlURLs crawls urls one after another, a url that fails does not stop
+// the rest. Pages that failed are reported with a partialError
+func crawlURLs(ctx context.Context, store db.Storage, urls []string, opts parser.Options) error {
+    failedURLs := 0
+    failed := 0
+
+    for _, url := range urls {
+        result, err := parser.Parse(ctx, store, url, opts)
+        failed += result.Stats.Failed
+
+        if errors.Is(err, context.Canceled) {
+            return err
+        }
+
+        if err != nil {
+            logs.Err(err)
+            failedURLs++
+            continue
+        }
+
+        logs.Infof("Successfully finished parsing %s", url)
+    }
+
+    if failedURLs > 0 {
+        return fmt.Errorf("[ERROR] Crawling %d of %d urls failed", failedURLs, len(urls))
+    }
+
+    if failed > 0 {
+        return &partialError{failed: failed}
+    }
+
+    return nil
+}
+
+func crawlOptions(cfg *config.Config) parser.Options {
+    c := cfg.Crawl
+
+    opts := parser.Options{
+        Workers: c.Workers,
+        RunTimeout: c.RunTimeout,
+        PageTimeout: c.PageTimeout,
+        Browsers: browser.Options{Size: c.Browsers, Tabs: c.BrowserTabs, MaxPages: c.BrowserMaxPages},
+        RateLimit: c.RateLimit,
+        RateBurst: c.RateBurst,
+        RateJitter: c.RateJitter,
+        Retry: retry.Policy{Attempts: c.RetryAttempts, Backoff: c.RetryBackoff, MaxBackoff: c.RetryMaxBackoff},
+        MarketRules: cfg.MarketRules,
+    }
+
+    if cfg.Adaptive.Enabled {
+        opts.Priority = &cfg.Adaptive.Policy
+    }
+
+    if cfg.Queue.Enabled {
+        opts.Queue = queueOptions(cfg)
+    }
+
+    return opts
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/schedule"
+)
+
+// daemon handles `crawler daemon [--addr :8080] [--sink spec]`: every source
+// with a schedule in the config is crawled on it until the process is
+// stopped. The endpoints of serve are available next to
+//
+//   GET /schedule    state of every scheduled source and its next crawl
+//
+// Past runs come from crawl_runs, so a restarted daemon knows when each
+// source was crawled last
+func daemon(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("daemon")
+    addr := fs.String("addr", ":8080", "address of the status endpoints, empty to not serve them")
+    sink := fs.String("sink", cfg.Sink, "where to write: postgres, sqlite[:path], memory or jsonl[:path] (db.driver when empty)")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    var jobs []schedule.Job
+    for code, src := range cfg.Sources {
+        if len(src.Schedule) > 0 {
+            jobs = append(jobs, schedule.Job{Source: code, Spec: src.Schedule, URLs: src.URLs})
+        }
+    }
+
+    if len(jobs) == 0 {
+        return usagef("[ERROR] No source has a schedule, set sources.<bookmaker>.schedule in the config")
+    }
+
+    for _, w := range cfg.Warnings() {
+        logs.Infof("Warning: %s", w)
+    }
+
+    store, err := open(cfg, *sink)
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    reader, ok := store.(db.Reader)
+    if !ok {
+        return fmt.Errorf("[ERROR] Selected storage can not be read from")
+    }
+
+    opts := crawlOptions(cfg)
+
+    opts.Archive, err = openArchive(cfg)
+    if err != nil {
+        return err
+    }
+
+    scheduler, err := schedule.GetScheduler(jobs, func(ctx context.Context, source string, urls []string) error {
+        err := crawlURLs(ctx, store, urls, opts)
+        pruneArchive(opts.Archive)
+
+        // the failed pages are in the stats of the run, like for crawl
+        var partial *partialError
+        if errors.As(err, &partial) {
+            logs.Err(err)
+            return nil
+        }
+
+        return err
+    })
+    if err != nil {
+        return err
+    }
+
+    last, err := reader.LastRuns(ctx)
+    if err != nil {
+        return err
+    }
+    scheduler.Restore(last)
+
+    // the scheduler stops too when the server can not start
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var wg sync.WaitGroup
+    var serveErr error
+
+    if len(*addr) > 0 {
+        mux := handler(reader)
+        mux.HandleFunc("/schedule", func(w http.ResponseWriter, r *http.Request) {
+            respond(w, scheduler.Status(), nil)
+        })
+
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            serveErr = listen(ctx, *addr, mux)
+            if serveErr != nil {
+                cancel()
+            }
+        } ()
+    }
+
+    logs.Infof("Crawling %d sources on schedule", len(jobs))
+
+    scheduler.Run(ctx)
+    wg.Wait()
+
+    return serveErr
+}
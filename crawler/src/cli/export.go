@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/db"
+)
+
+// row is an exported snapshot, price is null for suspended outcomes
+type row struct {
+    GameID int `json:"game_id"`
+    Date time.Time `json:"date"`
+    TeamA string `json:"team_a"`
+    TeamB string `json:"team_b"`
+    Tournament string `json:"tournament"`
+    Source string `json:"source"`
+    Market string `json:"market"`
+    Kind string `json:"kind"`
+    Outcome string `json:"outcome"`
+    Ordinal int `json:"ordinal"`
+    Price *float64 `json:"price"`
+    Suspended bool `json:"suspended"`
+    Value string `json:"value"`
+    RunID int64 `json:"run_id,omitempty"`
+    CapturedAt time.Time `json:"captured_at"`
+}
+
+var csvHeader = []string{
+    "game_id", "date", "team_a", "team_b", "tournament", "source", "market", "kind",
+    "outcome", "ordinal", "price", "suspended", "value", "run_id", "captured_at",
+}
+
+func (r row) csv() []string {
+    price := ""
+    if r.Price != nil {
+        price = strconv.FormatFloat(*r.Price, 'f', -1, 64)
+    }
+
+    return []string{
+        strconv.Itoa(r.GameID),
+        r.Date.UTC().Format(time.RFC3339),
+        r.TeamA,
+        r.TeamB,
+        r.Tournament,
+        r.Source,
+        r.Market,
+        r.Kind,
+        r.Outcome,
+        strconv.Itoa(r.Ordinal),
+        price,
+        strconv.FormatBool(r.Suspended),
+        r.Value,
+        strconv.FormatInt(r.RunID, 10),
+        r.CapturedAt.UTC().Format(time.RFC3339Nano),
+    }
+}
+
+func toRow(r db.Record) row {
+    res := row{
+        GameID: r.GameID,
+        Date: r.Date,
+        TeamA: r.TeamA,
+        TeamB: r.TeamB,
+        Tournament: r.Tournament,
+        Source: r.Source,
+        Market: r.Market,
+        Kind: r.Kind,
+        Outcome: r.Outcome,
+        Ordinal: r.Ordinal,
+        Suspended: r.Suspended,
+        Value: r.Value,
+        RunID: r.RunID,
+        CapturedAt: r.CapturedAt,
+    }
+
+    if r.Price.Valid {
+        price := r.Price.Float64
+        res.Price = &price
+    }
+
+    return res
+}
+
+// export handles `crawler export [--format jsonl|csv] [--source code]
+// [--since 24h|2006-01-02] [--limit n] [--out path] [--sink spec]`
+func export(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("export")
+    format := fs.String("format", "jsonl", "jsonl or csv")
+    source := fs.String("source", "", "only snapshots of this bookmaker (see `crawler sources`)")
+    since := fs.String("since", "", "only snapshots captured after this: a duration (24h) or a date (2006-01-02)")
+    limit := fs.Int("limit", 0, "at most this many snapshots")
+    out := fs.String("out", "-", "file to write to, - for stdout")
+    sink := fs.String("sink", "", "storage to read from: postgres or sqlite[:path] (db.driver when empty)")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    if *format != "jsonl" && *format != "csv" {
+        return usagef("[ERROR] Unknown format: %s (expected jsonl or csv)", *format)
+    }
+
+    filter := db.Filter{Source: *source, Limit: *limit}
+
+    if len(*since) > 0 {
+        filter.Since, err = parseSince(*since)
+        if err != nil {
+            return usagef("[ERROR] Invalid --since: %s", err.Error())
+        }
+    }
+
+    store, err := open(cfg, *sink)
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    reader, ok := store.(db.Reader)
+    if !ok {
+        return fmt.Errorf("[ERROR] Selected storage can not be read from")
+    }
+
+    records, err := reader.ListSnapshots(ctx, filter)
+    if err != nil {
+        return err
+    }
+
+    var w io.Writer = os.Stdout
+
+    if *out != "-" {
+        f, err := os.Create(*out)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+
+        w = f
+    }
+
+    if *format == "csv" {
+        cw := csv.NewWriter(w)
+        cw.Write(csvHeader)
+
+        for _, r := range records {
+            cw.Write(toRow(r).csv())
+        }
+
+        cw.Flush()
+
+        return cw.Error()
+    }
+
+    enc := json.NewEncoder(w)
+
+    for _, r := range records {
+        err = enc.Encode(toRow(r))
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func parseSince(s string) (time.Time, error) {
+    if d, err := time.ParseDuration(s); err == nil {
+        return time.Now().Add(-d), nil
+    }
+
+    for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+        if t, err := time.Parse(layout, s); err == nil {
+            return t, nil
+        }
+    }
+
+    return time.Time{}, fmt.Errorf("expected a duration like 24h or a date like 2006-01-02, got %q", s)
+}
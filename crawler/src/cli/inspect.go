@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+
+	"mxshs/crawler/src/browser"
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/core"
+	"mxshs/crawler/src/db"
+)
+
+// inspect handles `crawler inspect [--timeout 1m] <match url>`: the page is
+// loaded and parsed with the parser of its site and printed as JSON, nothing
+// is stored. Handy to check a parser against the live site
+func inspect(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("inspect")
+    timeout := fs.Duration("timeout", cfg.Crawl.PageTimeout, "time to wait for the page (crawl.page_timeout)")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    if fs.NArg() != 1 {
+        return usagef("[ERROR] Expected one match url")
+    }
+
+    url := fs.Arg(0)
+
+    reg, err := core.Lookup(url)
+    if err != nil {
+        return err
+    }
+
+    if !reg.Can(core.MatchOdds) {
+        return usagef("[ERROR] %s parser can not parse match pages", reg.Bookmaker.Name)
+    }
+
+    sink, err := db.GetJSONLSink("-")
+    if err != nil {
+        return err
+    }
+    defer sink.Close()
+
+    pool := browser.GetPool(browser.Options{Size: 1, Tabs: 1})
+    defer pool.Close()
+
+    p := reg.New(sink)
+    p.SetBrowsers(pool)
+    p.SetPageTimeout(*timeout)
+
+    return p.ParseAll(ctx, url)
+}
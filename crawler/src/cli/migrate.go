@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/db"
+)
+
+// migrate handles `crawler migrate [up | down [steps] | status]`
+func migrate(ctx context.Context, cfg *config.Config, args []string) error {
+    store, err := db.GetStorage(cfg.DB)
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    m, ok := store.(db.Migrator)
+    if !ok {
+        return fmt.Errorf("[ERROR] Selected storage has no schema to migrate")
+    }
+
+    cmd := "up"
+    if len(args) > 0 {
+        cmd = args[0]
+    }
+
+    switch cmd {
+    case "up":
+        err = m.Migrate()
+    case "down":
+        steps := 1
+        if len(args) > 1 {
+            steps, err = strconv.Atoi(args[1])
+            if err != nil || steps < 1 {
+                return usagef("[ERROR] Invalid number of steps: %s", args[1])
+            }
+        }
+        err = m.MigrateDown(steps)
+    case "status":
+        var status []db.MigrationStatus
+        status, err = m.MigrationStatus()
+        for _, s := range status {
+            applied := "pending"
+            if s.Applied {
+                applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+            }
+            fmt.Printf("%04d %-30s %s\n", s.Version, s.Name, applied)
+        }
+    default:
+        return usagef("[ERROR] Unknown migrate command: %s (expected up, down or status)", cmd)
+    }
+
+    return err
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/parser"
+)
+
+// work handles `crawler work [--source code] [--wait] [--concurrency n]`:
+// crawls the pages queued by crawls with the queue enabled, next to them or
+// after one of them crashed. With --wait it keeps looking for new pages
+// until it is stopped
+func work(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("work")
+    source := fs.String("source", "", "only crawl pages of this bookmaker code")
+    wait := fs.Bool("wait", false, "keep waiting for new pages instead of stopping once the queue is empty")
+    concurrency := fs.Int("concurrency", cfg.Crawl.Workers, "match pages loaded at once (crawl.workers)")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    if *concurrency < 1 {
+        return usagef("[ERROR] --concurrency must be at least 1")
+    }
+
+    store, err := open(cfg, "")
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    opts := crawlOptions(cfg)
+    opts.Workers = *concurrency
+    opts.Queue = queueOptions(cfg)
+
+    opts.Archive, err = openArchive(cfg)
+    if err != nil {
+        return err
+    }
+
+    var poll time.Duration
+    if *wait {
+        poll = cfg.Queue.Poll
+    }
+
+    stats, err := parser.Work(ctx, store, *source, opts, poll)
+    pruneArchive(opts.Archive)
+    if err != nil {
+        return err
+    }
+
+    if stats.Failed > 0 {
+        return &partialError{failed: stats.Failed}
+    }
+
+    return nil
+}
+
+// queue handles `crawler queue [status | requeue [source] | purge [--older 24h]]`
+func queue(ctx context.Context, cfg *config.Config, args []string) error {
+    cmd := "status"
+    if len(args) > 0 {
+        cmd, args = args[0], args[1:]
+    }
+
+    store, err := open(cfg, "")
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    q, ok := store.(db.Queue)
+    if !ok {
+        return fmt.Errorf("[ERROR] Selected storage has no job queue")
+    }
+
+    switch cmd {
+    case "status":
+        counts, err := q.QueueCounts(ctx)
+        if err != nil {
+            return err
+        }
+
+        if len(counts) == 0 {
+            fmt.Println("The queue is empty")
+        }
+
+        for _, c := range counts {
+            fmt.Printf("%-10s %-8s %d\n", c.Source, c.State, c.Jobs)
+        }
+    case "requeue":
+        if len(args) > 1 {
+            return usagef("[ERROR] Expected at most one bookmaker code")
+        }
+
+        source := ""
+        if len(args) == 1 {
+            source = args[0]
+        }
+
+        n, err := q.Requeue(ctx, source)
+        if err != nil {
+            return err
+        }
+
+        fmt.Printf("Requeued %d dead jobs\n", n)
+    case "purge":
+        fs := flags("queue purge")
+        older := fs.Duration("older", 24 * time.Hour, "delete done jobs finished longer ago than this")
+
+        err := parse(fs, args)
+        if err != nil {
+            return err
+        }
+
+        n, err := q.Purge(ctx, time.Now().UTC().Add(-*older))
+        if err != nil {
+            return err
+        }
+
+        fmt.Printf("Purged %d done jobs\n", n)
+    default:
+        return usagef("[ERROR] Unknown queue command: %s", cmd)
+    }
+
+    return nil
+}
+
+func queueOptions(cfg *config.Config) *parser.QueueOptions {
+    return &parser.QueueOptions{Lease: cfg.Queue.Lease, MaxAttempts: cfg.Queue.MaxAttempts}
+}
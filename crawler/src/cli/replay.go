@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mxshs/crawler/src/archive"
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/markets"
+	"mxshs/crawler/src/replay"
+	teamreg "mxshs/crawler/src/teams"
+)
+
+// replayCmd handles `crawler replay [--source code] [--url url] [--since 24h]
+// [--sink spec] [--diff=false]`: archived match pages are parsed again with
+// the current parsers, without Chrome, and compared with what the database
+// stored from them when they were crawled. With --sink the replayed games
+// are written there, e.g. after fixing a selector
+func replayCmd(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("replay")
+    source := fs.String("source", "", "only pages of this bookmaker code")
+    url := fs.String("url", "", "only captures of this match page")
+    since := fs.Duration("since", 24 * time.Hour, "only pages captured within this window, 0 for all")
+    sink := fs.String("sink", "", "write replayed games to postgres, sqlite[:path], memory or jsonl[:path], nothing is written when empty")
+    diff := fs.Bool("diff", true, "compare every page with what the database stored from it")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    if len(*sink) > 0 {
+        kind, arg, err := db.ParseSink(*sink)
+        if err != nil {
+            return usagef("%s", err.Error())
+        }
+
+        if kind == "jsonl" && len(arg) == 0 && *diff {
+            return usagef("[ERROR] The diff is printed to stdout, write to jsonl:<path> or pass --diff=false")
+        }
+    }
+
+    a, err := openArchive(cfg)
+    if err != nil {
+        return err
+    }
+
+    if a == nil {
+        return fmt.Errorf("[ERROR] The archive is disabled, set archive.dir in the config")
+    }
+
+    store, err := open(cfg, "")
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    known, err := store.LoadTeams()
+    if err != nil {
+        return err
+    }
+
+    classifier, err := markets.Load(cfg.MarketRules...)
+    if err != nil {
+        return err
+    }
+
+    opts := replay.Options{Teams: teamreg.GetRegistry(known), Markets: classifier}
+
+    if *diff {
+        reader, ok := store.(db.Reader)
+        if !ok {
+            return fmt.Errorf("[ERROR] Selected storage can not be read from")
+        }
+
+        opts.Stored = reader
+    }
+
+    if len(*sink) > 0 {
+        out, err := open(cfg, *sink)
+        if err != nil {
+            return err
+        }
+        defer out.Close()
+
+        opts.Sink = out
+    }
+
+    f := archive.Filter{Source: *source, URL: *url}
+    if *since > 0 {
+        f.Since = time.Now().Add(-*since)
+    }
+
+    sum, err := replay.Run(ctx, a, f, opts, func(res replay.Result) error {
+        e := res.Entry
+
+        switch {
+        case res.Err != nil:
+            logs.Errorf("Could not replay %s captured at %s: %s", e.URL, e.CapturedAt.Format(time.RFC3339), res.Err.Error())
+        case len(res.Diff) > 0:
+            fmt.Printf("%s %s (v%s, %s)\n", e.CapturedAt.Format(time.RFC3339), e.URL, e.ParserVersion, e.Hash[:12])
+            for _, c := range res.Diff {
+                fmt.Printf("    %s\n", c)
+            }
+        }
+
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    logs.Infof("Replayed %s", sum)
+
+    if sum.Failed > 0 {
+        return &partialError{failed: sum.Failed}
+    }
+
+    return nil
+}
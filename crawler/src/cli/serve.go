@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+)
+
+// serve handles `crawler serve [--addr :8080] [--sink spec]`:
+//
+//   GET /healthz                               ok when the storage responds
+//   GET /runs?limit=20                         latest crawl runs with their stats
+//   GET /snapshots?source=&since=24h&limit=    stored snapshots, 1000 at most
+func serve(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("serve")
+    addr := fs.String("addr", ":8080", "address to listen on")
+    sink := fs.String("sink", "", "storage to read from: postgres or sqlite[:path] (db.driver when empty)")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    store, err := open(cfg, *sink)
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    reader, ok := store.(db.Reader)
+    if !ok {
+        return fmt.Errorf("[ERROR] Selected storage can not be read from")
+    }
+
+    return listen(ctx, *addr, handler(reader))
+}
+
+// listen serves h on addr until ctx is done
+func listen(ctx context.Context, addr string, h http.Handler) error {
+    srv := &http.Server{Addr: addr, Handler: h}
+
+    go func() {
+        <-ctx.Done()
+
+        shutdown, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+        defer cancel()
+
+        srv.Shutdown(shutdown)
+    } ()
+
+    logs.Infof("Serving on %s", addr)
+
+    // stopping the server with a signal is how it normally exits
+    err := srv.ListenAndServe()
+    if errors.Is(err, http.ErrServerClosed) {
+        return nil
+    }
+
+    return err
+}
+
+func handler(reader db.Reader) *http.ServeMux {
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+        _, err := reader.ListRuns(r.Context(), 1)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusServiceUnavailable)
+            return
+        }
+
+        fmt.Fprintln(w, "ok")
+    })
+
+    mux.HandleFunc("/runs", func(w http.ResponseWriter, r *http.Request) {
+        limit, err := queryInt(r, "limit", 20)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+
+        runs, err := reader.ListRuns(r.Context(), limit)
+        if runs == nil {
+            runs = []db.Run{}
+        }
+
+        respond(w, runs, err)
+    })
+
+    mux.HandleFunc("/snapshots", func(w http.ResponseWriter, r *http.Request) {
+        limit, err := queryInt(r, "limit", 1000)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+
+        filter := db.Filter{Source: r.URL.Query().Get("source"), Limit: min(limit, 1000)}
+
+        if since := r.URL.Query().Get("since"); len(since) > 0 {
+            filter.Since, err = parseSince(since)
+            if err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+        }
+
+        records, err := reader.ListSnapshots(r.Context(), filter)
+
+        rows := make([]row, 0, len(records))
+        for _, rec := range records {
+            rows = append(rows, toRow(rec))
+        }
+
+        respond(w, rows, err)
+    })
+
+    return mux
+}
+
+func respond(w http.ResponseWriter, v any, err error) {
+    if err != nil {
+        logs.Err(err)
+        http.Error(w, "storage error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(v)
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+    raw := r.URL.Query().Get(name)
+    if len(raw) == 0 {
+        return def, nil
+    }
+
+    n, err := strconv.Atoi(raw)
+    if err != nil || n < 1 {
+        return 0, fmt.Errorf("%s must be a positive number", name)
+    }
+
+    return n, nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mxshs/crawler/src/config"
+	"mxshs/crawler/src/core"
+)
+
+// sources handles `crawler sources`
+func sources(ctx context.Context, cfg *config.Config, args []string) error {
+    fs := flags("sources")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    for _, r := range core.Registered() {
+        var caps []string
+        for _, c := range r.Capabilities {
+            caps = append(caps, string(c))
+        }
+
+        fmt.Printf(
+            "%-12s %-4s %-16s %-30s %s\n",
+            r.Bookmaker.Code,
+            "v" + r.Version,
+            r.Bookmaker.Name,
+            strings.Join(r.Hosts, ","),
+            strings.Join(caps, ","),
+        )
+    }
+
+    return nil
+}
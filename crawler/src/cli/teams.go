@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mxshs/crawler/src/config"
+)
+
+// teams handles `crawler teams [list | pending | add <team> | approve <alias> [team]]`
+func teams(ctx context.Context, cfg *config.Config, args []string) error {
+    store, err := open(cfg, "")
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+
+    cmd := "pending"
+    if len(args) > 0 {
+        cmd = args[0]
+    }
+
+    switch cmd {
+    case "list":
+        known, err := store.LoadTeams()
+        if err != nil {
+            return err
+        }
+
+        for _, t := range known {
+            fmt.Printf("%s\t%s\n", t.Name, strings.Join(t.Aliases, ", "))
+        }
+    case "pending":
+        pending, err := store.PendingTeams()
+        if err != nil {
+            return err
+        }
+
+        for _, s := range pending {
+            suggestion := "-"
+            if len(s.Team) > 0 {
+                suggestion = fmt.Sprintf("%s (%.2f)", s.Team, s.Score)
+            }
+            fmt.Printf("%-30s %-10s %s\n", s.Alias, s.Source, suggestion)
+        }
+    case "add":
+        if len(args) != 2 {
+            return usagef("[ERROR] Expected a team name")
+        }
+
+        return store.AddTeam(args[1])
+    case "approve":
+        if len(args) < 2 || len(args) > 3 {
+            return usagef("[ERROR] Expected an alias and optionally a team")
+        }
+
+        team := ""
+        if len(args) == 3 {
+            team = args[2]
+        } else {
+            // without a team the stored suggestion is approved
+            pending, err := store.PendingTeams()
+            if err != nil {
+                return err
+            }
+
+            for _, s := range pending {
+                if s.Alias == args[1] {
+                    team = s.Team
+                }
+            }
+
+            if len(team) == 0 {
+                return fmt.Errorf("[ERROR] No suggestion for %s, pass the team explicitly", args[1])
+            }
+        }
+
+        return store.ApproveAlias(args[1], team)
+    default:
+        return usagef("[ERROR] Unknown teams command: %s (expected list, pending, add or approve)", cmd)
+    }
+
+    return nil
+}
@@ -0,0 +1,345 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/priority"
+	"mxshs/crawler/src/schedule"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is read when no config file is given, it may be missing
+const DefaultPath = "crawler.yaml"
+
+// Config of the crawler. Settings come from Default, then the YAML file,
+// then the environment (see env.go), then command line flags
+type Config struct {
+    // LogLevel is debug, info or error
+    LogLevel string `yaml:"log_level"`
+    // Sink is where crawls write to: postgres, sqlite[:path], memory or
+    // jsonl[:path]. Empty means the storage selected by db.driver
+    Sink string `yaml:"sink"`
+    DB db.Config `yaml:"db"`
+    // Sources are crawled by `crawler crawl` when no url is given, by
+    // bookmaker code
+    Sources map[string]Source `yaml:"sources"`
+    Crawl Crawl `yaml:"crawl"`
+    Adaptive Adaptive `yaml:"adaptive"`
+    Queue Queue `yaml:"queue"`
+    Archive Archive `yaml:"archive"`
+    // MarketRules are extra market rule files, tried before the built in ones
+    MarketRules []string `yaml:"market_rules"`
+    // Sites are extra site definition files, replacing the built in one of
+    // the same bookmaker code
+    Sites []string `yaml:"sites"`
+    // BaseURLs point bookmakers (by code) at another site, e.g. a local fake
+    // of it, see ApplyBaseURLs
+    BaseURLs map[string]string `yaml:"base_urls,omitempty"`
+}
+
+type Source struct {
+    // URLs are listing pages with the matches to crawl
+    URLs []string `yaml:"urls"`
+    // Schedule of `crawler daemon`: a cron expression ("*/30 * * * *") or a
+    // descriptor ("@hourly", "@every 45m"). Unscheduled sources are only
+    // crawled on demand
+    Schedule string `yaml:"schedule,omitempty"`
+}
+
+type Crawl struct {
+    // match pages loaded at once
+    Workers int `yaml:"workers"`
+    // bounds the whole crawl, 0 means no limit
+    RunTimeout time.Duration `yaml:"run_timeout"`
+    // bounds loading a single page
+    PageTimeout time.Duration `yaml:"page_timeout"`
+    // Chrome processes kept running
+    Browsers int `yaml:"browsers"`
+    // pages open at once in each browser
+    BrowserTabs int `yaml:"browser_tabs"`
+    // pages before a browser is restarted, 0 never restarts it
+    BrowserMaxPages int `yaml:"browser_max_pages"`
+    // requests per second to each host, 0 disables limiting
+    RateLimit float64 `yaml:"rate_limit"`
+    RateBurst int `yaml:"rate_burst"`
+    // random extra delay before every request
+    RateJitter time.Duration `yaml:"rate_jitter"`
+    // attempts per page, only timeouts, network and storage errors are retried
+    RetryAttempts int `yaml:"retry_attempts"`
+    // wait before the first retry, doubled for each next one
+    RetryBackoff time.Duration `yaml:"retry_backoff"`
+    RetryMaxBackoff time.Duration `yaml:"retry_max_backoff"`
+}
+
+// Adaptive crawls only the matches of a listing that are due according to
+// their start time (see priority.Policy), meant for the daemon with a short
+// schedule. Without it every listed match is crawled on every run
+type Adaptive struct {
+    Enabled bool `yaml:"enabled"`
+    priority.Policy `yaml:",inline"`
+}
+
+// Queue keeps the match pages of every crawl as jobs in the database, so a
+// crashed crawl resumes where it stopped and `crawler work` processes can
+// share the pages of a crawl. The jsonl sink has no queue, the memory one
+// only lasts as long as the process
+type Queue struct {
+    Enabled bool `yaml:"enabled"`
+    // how long a worker holds a page, a page whose lease ran out is handed
+    // out again. Must be longer than loading a page with its retries
+    Lease time.Duration `yaml:"lease"`
+    // claims of a page before it goes to the dead letter state
+    MaxAttempts int `yaml:"max_attempts"`
+    // how often `crawler work --wait` looks for new pages
+    Poll time.Duration `yaml:"poll"`
+}
+
+// Archive keeps the HTML of every crawled listing and match page, gzipped
+// and stored once per distinct content, see archive.Archive
+type Archive struct {
+    // Dir of the archive, empty disables it
+    Dir string `yaml:"dir"`
+    // pages captured longer ago are deleted after a crawl, 0 keeps them
+    MaxAge time.Duration `yaml:"max_age"`
+    // size of the compressed pages in megabytes, the oldest days are deleted
+    // past it. 0 means no limit
+    MaxMB int `yaml:"max_mb"`
+}
+
+func Default() *Config {
+    return &Config{
+        LogLevel: "info",
+        DB: db.Config{Driver: "postgres", Port: "5432"},
+        Crawl: Crawl{
+            Workers: 3,
+            PageTimeout: time.Minute,
+            Browsers: 1,
+            BrowserTabs: 3,
+            BrowserMaxPages: 50,
+            RateLimit: 1,
+            RateBurst: 1,
+            RateJitter: 500 * time.Millisecond,
+            RetryAttempts: 3,
+            RetryBackoff: 2 * time.Second,
+            RetryMaxBackoff: time.Minute,
+        },
+        Adaptive: Adaptive{Policy: priority.Default()},
+        Queue: Queue{Lease: 10 * time.Minute, MaxAttempts: 3, Poll: 10 * time.Second},
+        Archive: Archive{Dir: "archive", MaxAge: 7 * 24 * time.Hour, MaxMB: 1024},
+    }
+}
+
+// Load reads the YAML file at path over the defaults and applies the
+// environment on top. A missing file is only an error when required, the
+// result is not validated
+func Load(path string, required bool) (*Config, error) {
+    cfg := Default()
+
+    raw, err := os.ReadFile(path)
+    switch {
+    case err == nil:
+        dec := yaml.NewDecoder(bytes.NewReader(raw))
+        dec.KnownFields(true)
+
+        err = dec.Decode(cfg)
+        if err != nil && !errors.Is(err, io.EOF) {
+            return nil, fmt.Errorf("[ERROR] Could not parse %s: %s", path, err.Error())
+        }
+    case errors.Is(err, fs.ErrNotExist) && !required:
+        logs.Debugf("No config file at %s, using defaults and the environment", path)
+    default:
+        return nil, fmt.Errorf("[ERROR] Could not read config %s: %s", path, err.Error())
+    }
+
+    err = applyEnv(cfg)
+    if err != nil {
+        return nil, err
+    }
+
+    return cfg, nil
+}
+
+// Validate reports every problem with the config at once
+func (c *Config) Validate() error {
+    var errs []string
+
+    check := func(ok bool, format string, args ...any) {
+        if !ok {
+            errs = append(errs, fmt.Sprintf(format, args...))
+        }
+    }
+
+    _, err := logs.ParseLevel(c.LogLevel)
+    check(err == nil, "log_level must be debug, info or error, got %q", c.LogLevel)
+
+    switch c.DB.Driver {
+    case "", "postgres", "memory":
+    case "sqlite":
+        check(len(c.DB.Path) > 0, "db.path is required for sqlite")
+    default:
+        check(false, "db.driver must be postgres, sqlite or memory, got %q", c.DB.Driver)
+    }
+
+    kind, arg, err := db.ParseSink(c.Sink)
+    check(err == nil, "sink must be postgres, sqlite[:path], memory or jsonl[:path], got %q", c.Sink)
+    check(kind != "sqlite" || len(arg) > 0 || len(c.DB.Path) > 0, "sink sqlite needs a path, as sqlite:<path> or db.path")
+
+    for _, code := range c.sourceCodes() {
+        b, ok := bookmaker.ByCode(code)
+        check(ok, "sources: unknown bookmaker %q", code)
+
+        src := c.Sources[code]
+        if len(src.Schedule) > 0 {
+            _, err := schedule.Parse(src.Schedule)
+            check(err == nil, "sources.%s.schedule: %q is not a cron expression or descriptor like @every 30m", code, src.Schedule)
+            check(len(src.URLs) > 0, "sources.%s: a scheduled source needs urls", code)
+        }
+
+        for _, raw := range src.URLs {
+            u, err := url.Parse(raw)
+            if err != nil || len(u.Hostname()) == 0 {
+                check(false, "sources.%s: %q is not an absolute url", code, raw)
+                continue
+            }
+
+            other, found := bookmaker.ByURL(raw)
+            check(!ok || (found && other.Code == b.Code), "sources.%s: %s is not a %s page", code, raw, b.Name)
+        }
+    }
+
+    cr := c.Crawl
+    check(cr.Workers >= 1, "crawl.workers must be at least 1, got %d", cr.Workers)
+    check(cr.RunTimeout >= 0, "crawl.run_timeout must not be negative")
+    check(cr.PageTimeout > 0, "crawl.page_timeout must be positive")
+    check(cr.Browsers >= 1, "crawl.browsers must be at least 1, got %d", cr.Browsers)
+    check(cr.BrowserTabs >= 1, "crawl.browser_tabs must be at least 1, got %d", cr.BrowserTabs)
+    check(cr.BrowserMaxPages >= 0, "crawl.browser_max_pages must not be negative")
+    check(cr.RateLimit >= 0, "crawl.rate_limit must not be negative")
+    check(cr.RateBurst >= 1, "crawl.rate_burst must be at least 1, got %d", cr.RateBurst)
+    check(cr.RateJitter >= 0, "crawl.rate_jitter must not be negative")
+    check(cr.RetryAttempts >= 1, "crawl.retry_attempts must be at least 1, got %d", cr.RetryAttempts)
+    check(cr.RetryBackoff >= 0, "crawl.retry_backoff must not be negative")
+    check(cr.RetryMaxBackoff >= cr.RetryBackoff, "crawl.retry_max_backoff must not be less than crawl.retry_backoff")
+
+    if c.Adaptive.Enabled {
+        err := c.Adaptive.Validate()
+        check(err == nil, "adaptive: %v", err)
+    }
+
+    q := c.Queue
+    check(q.Lease > cr.PageTimeout, "queue.lease must be longer than crawl.page_timeout")
+    check(q.MaxAttempts >= 1, "queue.max_attempts must be at least 1, got %d", q.MaxAttempts)
+    check(q.Poll > 0, "queue.poll must be positive")
+    check(!q.Enabled || kind != "jsonl", "queue can not be used with the jsonl sink")
+
+    check(c.Archive.MaxAge >= 0, "archive.max_age must not be negative")
+    check(c.Archive.MaxMB >= 0, "archive.max_mb must not be negative")
+
+    if len(errs) > 0 {
+        return fmt.Errorf("[ERROR] Invalid config:\n  %s", strings.Join(errs, "\n  "))
+    }
+
+    return nil
+}
+
+// Warnings lists settings that are valid but likely not what was meant:
+// with adaptive crawling, scheduled sources that run less often than the
+// shortest interval of the policy
+func (c *Config) Warnings() []string {
+    var res []string
+
+    if !c.Adaptive.Enabled {
+        return res
+    }
+
+    shortest := c.Adaptive.Shortest()
+
+    for _, code := range c.sourceCodes() {
+        spec := c.Sources[code].Schedule
+        if len(spec) == 0 {
+            continue
+        }
+
+        s, err := schedule.Parse(spec)
+        if err != nil {
+            continue
+        }
+
+        if gap := schedule.MaxGap(s, time.Now()); gap > shortest {
+            res = append(res, fmt.Sprintf(
+                "sources.%s.schedule: %q waits up to %s between crawls, matches due every %s (adaptive) are crawled only that often",
+                code, spec, gap, shortest,
+            ))
+        }
+    }
+
+    return res
+}
+
+// ApplyBaseURLs overrides the urls of the bookmakers listed in base_urls,
+// it has to run before Validate so sources on the overridden hosts pass
+func (c *Config) ApplyBaseURLs() error {
+    codes := make([]string, 0, len(c.BaseURLs))
+    for code := range c.BaseURLs {
+        codes = append(codes, code)
+    }
+    sort.Strings(codes)
+
+    for _, code := range codes {
+        err := bookmaker.Override(code, c.BaseURLs[code])
+        if err != nil {
+            return fmt.Errorf("[ERROR] Invalid config: base_urls: %s", strings.TrimPrefix(err.Error(), "[ERROR] "))
+        }
+    }
+
+    return nil
+}
+
+// StartURLs lists the urls of every source, ordered by bookmaker code
+func (c *Config) StartURLs() []string {
+    var res []string
+
+    for _, code := range c.sourceCodes() {
+        res = append(res, c.Sources[code].URLs...)
+    }
+
+    return res
+}
+
+func (c *Config) sourceCodes() []string {
+    var codes []string
+    for code := range c.Sources {
+        codes = append(codes, code)
+    }
+    sort.Strings(codes)
+
+    return codes
+}
+
+// String formats the config as YAML with the database password hidden
+func (c *Config) String() string {
+    masked := *c
+    if len(masked.DB.Pass) > 0 {
+        masked.DB.Pass = "********"
+    }
+
+    out, err := yaml.Marshal(&masked)
+    if err != nil {
+        return err.Error()
+    }
+
+    return string(out)
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"mxshs/crawler/src/priority"
+)
+
+func TestWarnings(t *testing.T) {
+    cases := []struct {
+        name string
+        adaptive bool
+        schedule string
+        warn bool
+    }{
+        {"not adaptive", false, "*/30 * * * *", false},
+        {"slower than the shortest tier", true, "*/30 * * * *", true},
+        {"as fast as the shortest tier", true, "@every 2m", false},
+        {"unscheduled", true, "", false},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            cfg := Default()
+            cfg.Adaptive = Adaptive{Enabled: c.adaptive, Policy: priority.Default()}
+            cfg.Sources = map[string]Source{
+                "leon": {URLs: []string{"https://leon.ru/bets/esports/dota-2"}, Schedule: c.schedule},
+            }
+
+            warnings := cfg.Warnings()
+            if got := len(warnings) > 0; got != c.warn {
+                t.Fatalf("warnings %q, want a warning: %t", warnings, c.warn)
+            }
+
+            if c.warn && !strings.HasPrefix(warnings[0], "sources.leon.schedule") {
+                t.Errorf("warning %q does not name the source", warnings[0])
+            }
+        })
+    }
+}
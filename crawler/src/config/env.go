@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables override the config file, so containers can be
+// configured without one
+func applyEnv(c *Config) error {
+    strs := map[string]*string{
+        "LOG_LEVEL": &c.LogLevel,
+        "SINK": &c.Sink,
+        "DB_DRIVER": &c.DB.Driver,
+        "DB_HOST": &c.DB.Host,
+        "DB_PORT": &c.DB.Port,
+        "DB_USER": &c.DB.User,
+        "DB_PASS": &c.DB.Pass,
+        "DB": &c.DB.Name,
+        "DB_PATH": &c.DB.Path,
+        "ARCHIVE_DIR": &c.Archive.Dir,
+    }
+
+    for name, field := range strs {
+        if raw, ok := lookup(name); ok {
+            *field = raw
+        }
+    }
+
+    ints := map[string]*int{
+        "WORKERS": &c.Crawl.Workers,
+        "BROWSERS": &c.Crawl.Browsers,
+        "BROWSER_TABS": &c.Crawl.BrowserTabs,
+        "BROWSER_MAX_PAGES": &c.Crawl.BrowserMaxPages,
+        "RATE_BURST": &c.Crawl.RateBurst,
+        "RETRY_ATTEMPTS": &c.Crawl.RetryAttempts,
+        "QUEUE_MAX_ATTEMPTS": &c.Queue.MaxAttempts,
+        "ARCHIVE_MAX_MB": &c.Archive.MaxMB,
+    }
+
+    for name, field := range ints {
+        err := envInt(name, field)
+        if err != nil {
+            return err
+        }
+    }
+
+    durations := map[string]*time.Duration{
+        "RUN_TIMEOUT": &c.Crawl.RunTimeout,
+        "PAGE_TIMEOUT": &c.Crawl.PageTimeout,
+        "RATE_JITTER": &c.Crawl.RateJitter,
+        "RETRY_BACKOFF": &c.Crawl.RetryBackoff,
+        "RETRY_MAX_BACKOFF": &c.Crawl.RetryMaxBackoff,
+        "QUEUE_LEASE": &c.Queue.Lease,
+        "QUEUE_POLL": &c.Queue.Poll,
+        "ARCHIVE_MAX_AGE": &c.Archive.MaxAge,
+    }
+
+    for name, field := range durations {
+        err := envDuration(name, field)
+        if err != nil {
+            return err
+        }
+    }
+
+    if raw, ok := lookup("RATE_LIMIT"); ok {
+        n, err := strconv.ParseFloat(raw, 64)
+        if err != nil {
+            return fmt.Errorf("[ERROR] RATE_LIMIT must be a number, got %q", raw)
+        }
+        c.Crawl.RateLimit = n
+    }
+
+    if raw, ok := lookup("ADAPTIVE"); ok {
+        on, err := strconv.ParseBool(raw)
+        if err != nil {
+            return fmt.Errorf("[ERROR] ADAPTIVE must be true or false, got %q", raw)
+        }
+        c.Adaptive.Enabled = on
+    }
+
+    if raw, ok := lookup("QUEUE"); ok {
+        on, err := strconv.ParseBool(raw)
+        if err != nil {
+            return fmt.Errorf("[ERROR] QUEUE must be true or false, got %q", raw)
+        }
+        c.Queue.Enabled = on
+    }
+
+    // comma separated
+    if raw, ok := lookup("MARKET_RULES"); ok {
+        c.MarketRules = nil
+        for _, path := range strings.Split(raw, ",") {
+            if path = strings.TrimSpace(path); len(path) > 0 {
+                c.MarketRules = append(c.MarketRules, path)
+            }
+        }
+    }
+
+    // comma separated
+    if raw, ok := lookup("SITES"); ok {
+        c.Sites = nil
+        for _, path := range strings.Split(raw, ",") {
+            if path = strings.TrimSpace(path); len(path) > 0 {
+                c.Sites = append(c.Sites, path)
+            }
+        }
+    }
+
+    return nil
+}
+
+// lookup ignores variables that are set but empty
+func lookup(name string) (string, bool) {
+    raw := strings.TrimSpace(os.Getenv(name))
+
+    return raw, len(raw) > 0
+}
+
+func envInt(name string, field *int) error {
+    raw, ok := lookup(name)
+    if !ok {
+        return nil
+    }
+
+    n, err := strconv.Atoi(raw)
+    if err != nil {
+        return fmt.Errorf("[ERROR] %s must be a number, got %q", name, raw)
+    }
+    *field = n
+
+    return nil
+}
+
+func envDuration(name string, field *time.Duration) error {
+    raw, ok := lookup(name)
+    if !ok {
+        return nil
+    }
+
+    d, err := time.ParseDuration(raw)
+    if err != nil {
+        return fmt.Errorf("[ERROR] %s must be a duration like 500ms, got %q", name, raw)
+    }
+    *field = d
+
+    return nil
+}
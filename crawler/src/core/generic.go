@@ -0,0 +1,361 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/archive"
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/retry"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+)
+
+func GetSiteParser(site Site, db db.Storage) BetParser {
+    parser := SiteParser{Site: site}
+    parser.DB = db
+
+    return &parser
+}
+
+// SiteParser crawls any site by its definition, layout changes are an edit
+// of the site's YAML file
+type SiteParser struct {
+    Parser
+    Site Site
+    DB db.Storage
+}
+
+// baseURL of the site, it may have been overridden after the parser was
+// built (see bookmaker.Override)
+func (sp *SiteParser) baseURL() string {
+    if b, ok := bookmaker.ByCode(sp.Site.Code); ok {
+        return b.BaseURL
+    }
+
+    return sp.Site.BaseURL
+}
+
+// load navigates a tab to url and reads the HTML of the page as l says
+func (sp *SiteParser) load(ctx context.Context, url string, l Load) (string, error) {
+    page, release, err := sp.tab(ctx)
+    if err != nil {
+        return "", err
+    }
+    defer release()
+
+    err = navigate(page, url)
+    if err != nil {
+        return "", err
+    }
+
+    wait := chromedp.WaitReady(l.Wait, chromedp.ByQuery)
+    if l.Visible {
+        wait = chromedp.WaitVisible(l.Wait, chromedp.ByQuery)
+    }
+
+    actions := []chromedp.Action{wait}
+
+    if len(l.Click) > 0 {
+        actions = append(
+            actions,
+            chromedp.Click(l.Click, chromedp.ByQuery),
+            chromedp.Sleep(l.Settle),
+        )
+    }
+
+    var domNode string
+
+    actions = append(
+        actions,
+        chromedp.WaitReady(l.Read, chromedp.ByQuery),
+        chromedp.InnerHTML(l.Read, &domNode),
+    )
+
+    err = chromedp.Run(page, actions...)
+    if err != nil {
+        return "", waitErr(err)
+    }
+
+    return domNode, nil
+}
+
+func (sp *SiteParser) ParseMatchUrls(ctx context.Context, url string) ([]string, error) {
+    domNode, err := sp.load(ctx, url, sp.Site.Listing.Load)
+    if err != nil {
+        return nil, err
+    }
+
+    sp.keep(archive.Listing, sp.Site.Code, url, domNode)
+
+    return sp.ExtractMatchUrls(domNode)
+}
+
+// ExtractMatchUrls collects the match pages linked from the HTML of a
+// listing page, as they are linked unless the listing says absolute
+func (sp *SiteParser) ExtractMatchUrls(html string) ([]string, error) {
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+    if err != nil {
+        return nil, err
+    }
+
+    var urls []string
+    missing := 0
+
+    doc.Find(sp.Site.Listing.Match).Each(
+        func(i int, s *goquery.Selection) {
+            if len(sp.Site.Listing.Link) > 0 {
+                s = s.Find(sp.Site.Listing.Link).First()
+            }
+
+            if url, ok := s.Attr("href"); ok {
+                if sp.Site.Listing.Absolute {
+                    url = absURL(sp.baseURL(), url)
+                }
+
+                urls = append(urls, url)
+            } else {
+                missing++
+            }
+        },
+    )
+
+    if missing > 0 {
+        logs.Errorf("%d matches listed without a link on %s (possibly HTML changed)", missing, sp.Site.Code)
+    }
+
+    return urls, nil
+}
+
+func (sp *SiteParser) ParseAll(ctx context.Context, url string) error {
+    pageURL := absURL(sp.baseURL(), url)
+
+    domNode, err := sp.load(ctx, pageURL, sp.Site.Match)
+    if err != nil {
+        return err
+    }
+
+    sp.keep(archive.Match, sp.Site.Code, pageURL, domNode)
+
+    game, err := sp.Extract(domNode, pageURL)
+    if err != nil {
+        return err
+    }
+
+    ctx, cancel := sp.writeCtx(ctx)
+    defer cancel()
+
+    _, err = sp.DB.SaveGameBets(ctx, game)
+
+    return retry.Wrap(retry.Storage, err)
+}
+
+// Extract parses the HTML of a match page loaded from url
+func (sp *SiteParser) Extract(html string, url string) (*domain.GameBets, error) {
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+    if err != nil {
+        return nil, retry.Wrap(retry.Parse, err)
+    }
+
+    game, err := sp.ParseMatchData(doc.Selection)
+    if err != nil {
+        return nil, retry.Wrap(retry.Parse, err)
+    }
+
+    err = sp.ParseMatchBets(game, doc.Selection)
+    if err != nil {
+        return nil, retry.Wrap(retry.Parse, err)
+    }
+
+    game.Source = sp.Site.Code
+    game.SourceURL = url
+    game.RunID = sp.RunID
+
+    return game, nil
+}
+
+func (sp *SiteParser) ParseMatchData(s *goquery.Selection) (*domain.GameBets, error) {
+    sel := sp.Site.Selector
+
+    game := &domain.GameBets{}
+
+    var teams []string
+    s.Find(sel.TeamName).Each(
+        func(i int, s *goquery.Selection) {
+            teams = append(teams, strings.TrimSpace(s.Text()))
+        },
+    )
+
+    if len(teams) != 2 {
+        return nil, fmt.Errorf(
+            "[ERROR] Number of parsed teams: %d, expected: %d\n",
+            len(teams),
+            2,
+        )
+    }
+
+    if len(teams[0]) == 0 || len(teams[1]) == 0 {
+        return nil, fmt.Errorf(
+            "[ERROR] Could not parse team names (got zero-length values)\n",
+        )
+    }
+
+    if sel.ReverseTeams {
+        teams[0], teams[1] = teams[1], teams[0]
+    }
+
+    game.TeamA = sp.team(teams[0], sp.Site.Code)
+    game.TeamB = sp.team(teams[1], sp.Site.Code)
+
+    var dateNode []string
+    if len(sel.MatchDate) > 0 {
+        s.Find(sel.MatchDate).Each(
+            func(i int, s *goquery.Selection) {
+                if text := strings.TrimSpace(s.Text()); len(text) > 0 {
+                    dateNode = append(dateNode, text)
+                }
+            },
+        )
+    }
+
+    date, err := sp.parseDate(dateNode)
+    if err != nil {
+        return nil, err
+    }
+
+    game.Date = date
+
+    if len(sel.MatchTournament) > 0 {
+        game.Tournament = strings.TrimSpace(s.Find(sel.MatchTournament).Eq(sel.MatchTournamentIndex).Text())
+    }
+
+    return game, nil
+}
+
+func (sp *SiteParser) ParseMatchBets(game *domain.GameBets, s *goquery.Selection) error {
+    sel := sp.Site.Selector
+
+    captured := sp.now()
+
+    s.Find(sel.BetDiv).Each(func(i int, s *goquery.Selection) {
+        bet := &domain.Bet{}
+
+        bet.Type = strings.TrimSpace(s.Find(sel.BetTitle).First().Text())
+
+        s.Find(sel.BetOutcome).Each(func(i int, s *goquery.Selection) {
+            option := newOption(
+                s.Find(sel.BetOpt).First().Text(),
+                s.Find(sel.BetCoef).First().Text(),
+                sp.Site.Code,
+                captured,
+            )
+
+            bet.Opts = append(bet.Opts, option)
+        })
+
+        sp.classify(bet)
+
+        game.Bets = append(game.Bets, *bet)
+    })
+
+    return nil
+}
+
+// parseDate reads the start of a match from the texts of its date elements
+// as the site's Dates say
+func (sp *SiteParser) parseDate(d []string) (time.Time, error) {
+    dates := sp.Site.Dates
+    now := sp.now()
+
+    if len(d) == 0 {
+        if dates.Missing == MissingNow {
+            logs.Infof("No match date found on %s, using the current time", sp.Site.Code)
+            return now, nil
+        }
+
+        return time.Time{}, fmt.Errorf("[ERROR] No match date found (possibly HTML changed)")
+    }
+
+    // longer month prefixes first, so they win over shorter ones
+    prefixes := make([]string, 0, len(dates.Months))
+    for prefix := range dates.Months {
+        prefixes = append(prefixes, prefix)
+    }
+    sort.Slice(prefixes, func(i, j int) bool {
+        return len(prefixes[i]) > len(prefixes[j])
+    })
+
+    words := strings.Fields(strings.Join(d, " "))
+
+    for i, word := range words {
+        if days, ok := dates.Relative[word]; ok {
+            words[i] = now.AddDate(0, 0, days).Format("2.1.2006")
+            continue
+        }
+
+        for _, prefix := range prefixes {
+            if strings.HasPrefix(strings.ToLower(word), strings.ToLower(prefix)) {
+                words[i] = dates.Months[prefix]
+                break
+            }
+        }
+    }
+
+    text := strings.Join(words, " ")
+
+    for _, layout := range dates.Layouts {
+        t, err := time.Parse(layout, text)
+        if err != nil {
+            continue
+        }
+
+        if t.Year() == 0 {
+            t, err = nearestYear(t, now)
+            if err != nil {
+                return time.Time{}, err
+            }
+        }
+
+        return t, nil
+    }
+
+    return time.Time{}, fmt.Errorf(
+        "[ERROR] Failed to convert date %q, expected one of: %s (possibly HTML changed)",
+        text,
+        strings.Join(dates.Layouts, ", "),
+    )
+}
+
+// nearestYear dates t, parsed without a year, in the year that puts it
+// closest to now: "31.12" seen on the 1st of January is last year's match
+// and "01.01" seen on the 31st of December next year's. Years without the
+// day ("29.02" outside leap years) are skipped
+func nearestYear(t time.Time, now time.Time) (time.Time, error) {
+    var best time.Time
+
+    for _, year := range []int{now.Year(), now.Year() - 1, now.Year() + 1} {
+        c := time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+        if c.Day() != t.Day() {
+            continue
+        }
+
+        if best.IsZero() || c.Sub(now).Abs() < best.Sub(now).Abs() {
+            best = c
+        }
+    }
+
+    if best.IsZero() {
+        return time.Time{}, fmt.Errorf(
+            "[ERROR] No year around %d has the date %s", now.Year(), t.Format("02.01"),
+        )
+    }
+
+    return best, nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseDate(t *testing.T) {
+    site := Site{
+        Code: "test",
+        Dates: Dates{
+            Layouts: []string{"15:04 01/02", "15:04 2.1.2006", "2 Jan 2006 15:04"},
+            Months: map[string]string{"Мар": "Mar", "Март": "Mar"},
+            Relative: map[string]int{"Сегодня": 0, "Завтра": 1},
+        },
+    }
+
+    cases := []struct {
+        name string
+        now time.Time
+        date []string
+        want time.Time
+    }{
+        {"full date", fixtureTime, []string{"14 Мар 2026", "18:30"}, time.Date(2026, time.March, 14, 18, 30, 0, 0, time.UTC)},
+        {"without year", fixtureTime, []string{"18:30", "03/14"}, time.Date(2026, time.March, 14, 18, 30, 0, 0, time.UTC)},
+        {"today", fixtureTime, []string{"18:30", "Сегодня"}, time.Date(2026, time.March, 14, 18, 30, 0, 0, time.UTC)},
+        {"tomorrow", fixtureTime, []string{"18:30", "Завтра"}, time.Date(2026, time.March, 15, 18, 30, 0, 0, time.UTC)},
+        {
+            "next year's match on new year's eve",
+            time.Date(2026, time.December, 31, 22, 0, 0, 0, time.UTC),
+            []string{"01:00", "01/01"},
+            time.Date(2027, time.January, 1, 1, 0, 0, 0, time.UTC),
+        },
+        {
+            "last year's match on new year's day",
+            time.Date(2027, time.January, 1, 1, 0, 0, 0, time.UTC),
+            []string{"23:00", "12/31"},
+            time.Date(2026, time.December, 31, 23, 0, 0, 0, time.UTC),
+        },
+        {
+            "leap day in the leap year before",
+            time.Date(2029, time.January, 10, 12, 0, 0, 0, time.UTC),
+            []string{"18:00", "02/29"},
+            time.Date(2028, time.February, 29, 18, 0, 0, 0, time.UTC),
+        },
+        {
+            "leap day in the leap year after",
+            time.Date(2027, time.December, 20, 12, 0, 0, 0, time.UTC),
+            []string{"18:00", "02/29"},
+            time.Date(2028, time.February, 29, 18, 0, 0, 0, time.UTC),
+        },
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            sp := &SiteParser{Site: site}
+            sp.SetClock(func() time.Time { return c.now })
+
+            got, err := sp.parseDate(c.date)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if !got.Equal(c.want) {
+                t.Errorf("got %s, want %s", got, c.want)
+            }
+        })
+    }
+}
+
+func TestParseDateFails(t *testing.T) {
+    sp := &SiteParser{Site: Site{Code: "test", Dates: Dates{Layouts: []string{"15:04 2.1.2006", "15:04 2.1"}}}}
+    sp.SetClock(func() time.Time { return fixtureTime })
+
+    // no year around 2026 has a 29th of February
+    for _, date := range [][]string{nil, {"soon"}, {"18:30"}, {"18:00 29.2"}} {
+        if got, err := sp.parseDate(date); err == nil {
+            t.Errorf("parseDate(%q) = %s, want an error", date, got)
+        }
+    }
+}
+
+func TestParseDateMissing(t *testing.T) {
+    sp := &SiteParser{Site: Site{Code: "test", Dates: Dates{Layouts: []string{"15:04 2.1.2006"}, Missing: MissingNow}}}
+    sp.SetClock(func() time.Time { return fixtureTime })
+
+    got, err := sp.parseDate(nil)
+    if err != nil || !got.Equal(fixtureTime) {
+        t.Errorf("parseDate(nil) = %s, %v, want %s", got, err, fixtureTime)
+    }
+
+    // a date that is there but unreadable still fails
+    if got, err := sp.parseDate([]string{"soon"}); err == nil {
+        t.Errorf("parseDate(soon) = %s, want an error", got)
+    }
+}
+
+func TestTournamentIndex(t *testing.T) {
+    // every title in its own item, then a trailing div that is no title
+    html := `<div class="page"><nav class="breadcrumb"><ul>
+        <li><a><div class="breadcrumb__title">Киберспорт</div></a></li>
+        <li><a><div class="breadcrumb__title">Dota 2</div></a></li>
+        <li><a><div class="breadcrumb__title">DreamLeague</div></a></li>
+        <li><div class="breadcrumb__title">Team Spirit - OG</div></li>
+    </ul><div class="breadcrumb__actions"></div></nav>
+    <div class="team">Team Spirit</div><div class="team">OG</div>
+    <div class="date"><span>18:30 14.3.2026</span></div></div>`
+
+    cases := []struct {
+        index int
+        want string
+    }{
+        {0, "Киберспорт"},
+        {2, "DreamLeague"},
+        {-2, "DreamLeague"},
+        {-1, "Team Spirit - OG"},
+        {9, ""},
+    }
+
+    for _, c := range cases {
+        sp := &SiteParser{Site: Site{
+            Code: "test",
+            Selector: Selector{
+                TeamName: ".team",
+                MatchDate: ".date > span",
+                MatchTournament: "div .breadcrumb__title",
+                MatchTournamentIndex: c.index,
+            },
+            Dates: Dates{Layouts: []string{"15:04 2.1.2006"}},
+        }}
+
+        doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        game, err := sp.ParseMatchData(doc.Selection)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if game.Tournament != c.want {
+            t.Errorf("index %d: got %q, want %q", c.index, game.Tournament, c.want)
+        }
+    }
+}
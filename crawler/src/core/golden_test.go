@@ -0,0 +1,118 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// go test ./src/core -update rewrites the golden files from what the
+// parsers extract now, review the diff before committing it
+var update = flag.Bool("update", false, "rewrite the golden files from the current output")
+
+// clock every fixture is parsed at, so captured times and relative dates
+// like "Today" do not depend on when the test runs
+var fixtureTime = time.Date(2026, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+// TestGolden runs the saved pages in testdata/<bookmaker code>/ through the
+// extraction of the registered parser: listing*.html through
+// ExtractMatchUrls, every other page through Extract. The output is
+// compared with the .golden.json file next to the page
+func TestGolden(t *testing.T) {
+    for _, reg := range Registered() {
+        pages, err := filepath.Glob(filepath.Join("testdata", reg.Bookmaker.Code, "*.html"))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if len(pages) == 0 {
+            t.Errorf("%s has no fixture pages in testdata/%s", reg.Bookmaker.Code, reg.Bookmaker.Code)
+        }
+
+        for _, page := range pages {
+            name := strings.TrimSuffix(filepath.Base(page), ".html")
+
+            t.Run(reg.Bookmaker.Code + "/" + name, func(t *testing.T) {
+                html, err := os.ReadFile(page)
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                p := reg.New(nil)
+                p.SetClock(func() time.Time {
+                    return fixtureTime
+                })
+
+                var got any
+                if strings.HasPrefix(name, "listing") {
+                    got, err = p.ExtractMatchUrls(string(html))
+                } else {
+                    got, err = p.Extract(string(html), reg.Bookmaker.BaseURL + "/" + name)
+                }
+                if err != nil {
+                    t.Fatalf("extracting %s: %s", page, err.Error())
+                }
+
+                compareGolden(t, strings.TrimSuffix(page, ".html") + ".golden.json", got)
+            })
+        }
+    }
+}
+
+func compareGolden(t *testing.T, path string, got any) {
+    t.Helper()
+
+    out, err := json.MarshalIndent(got, "", "  ")
+    if err != nil {
+        t.Fatal(err)
+    }
+    out = append(out, '\n')
+
+    if *update {
+        err := os.WriteFile(path, out, 0o644)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        return
+    }
+
+    want, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("%s, run with -update to create it", err.Error())
+    }
+
+    if !bytes.Equal(want, out) {
+        t.Errorf("output differs from %s, run with -update if the change is intended:\n%s", path, lineDiff(string(want), string(out)))
+    }
+}
+
+// lineDiff lists the lines of got that differ from want by position,
+// enough to spot a changed field in indented JSON
+func lineDiff(want string, got string) string {
+    w := strings.Split(want, "\n")
+    g := strings.Split(got, "\n")
+
+    var b strings.Builder
+
+    for i := 0; i < max(len(w), len(g)); i++ {
+        var wl, gl string
+        if i < len(w) {
+            wl = w[i]
+        }
+        if i < len(g) {
+            gl = g[i]
+        }
+
+        if wl != gl {
+            b.WriteString("- " + wl + "\n+ " + gl + "\n")
+        }
+    }
+
+    return b.String()
+}
@@ -1,26 +1,160 @@
 package core
 
 import (
+	"context"
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/archive"
+	"mxshs/crawler/src/browser"
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/markets"
+	"mxshs/crawler/src/teams"
+
     "github.com/PuerkitoBio/goquery"
 )
 
 type BetParser interface {
-    ParseMatchUrls(url string) ([]string, error)
-    ParseAll(url string) error
-    ParseMatchData(s *goquery.Selection) (int, error)
-    ParseMatchBets(id int, s *goquery.Selection) error
+    // ParseMatchUrls and ParseAll stop loading pages once ctx is done
+    ParseMatchUrls(ctx context.Context, url string) ([]string, error)
+    ParseAll(ctx context.Context, url string) error
+    // ParseMatchData and ParseMatchBets only extract data, ParseAll persists
+    // the resulting game with all of its bets at once
+    ParseMatchData(s *goquery.Selection) (*domain.GameBets, error)
+    ParseMatchBets(game *domain.GameBets, s *goquery.Selection) error
+    // ExtractMatchUrls and Extract parse the HTML of a listing and of a
+    // match page loaded from url without touching the browser or the
+    // storage, ParseMatchUrls and ParseAll call them once a page is loaded
+    ExtractMatchUrls(html string) ([]string, error)
+    Extract(html string, url string) (*domain.GameBets, error)
+    SetRunID(id int64)
+    SetTeams(r *teams.Registry)
+    SetMarkets(c *markets.Classifier)
+    SetBrowsers(p *browser.Pool)
+    SetPageTimeout(d time.Duration)
+    SetArchive(a *archive.Archive)
+    SetClock(now func() time.Time)
 }
 
+// writes of a parsed page may finish this long after the crawl was cancelled
+const writeTimeout = 30 * time.Second
+
+// Parser holds state shared by every site parser
 type Parser struct {
+    // RunID is the crawl run stored games and snapshots are attributed to
+    RunID int64
+    // Teams maps scraped team names to canonical ones, names are only
+    // trimmed when it is not set
+    Teams *teams.Registry
+    // Markets classifies bet titles, built in rules are used when it is not set
+    Markets *markets.Classifier
+    // Browsers hands out tabs for loading pages, the shared default pool is
+    // used when it is not set
+    Browsers *browser.Pool
+    // PageTimeout bounds loading a single page, 0 waits as long as ctx allows
+    PageTimeout time.Duration
+    // Archive keeps the HTML of every loaded page, nothing is kept when it
+    // is not set
+    Archive *archive.Archive
+    // Clock is the time odds are captured at and relative dates ("Today")
+    // are resolved against, time.Now when it is not set
+    Clock func() time.Time
+}
+
+func (p *Parser) SetRunID(id int64) {
+    p.RunID = id
+}
+
+func (p *Parser) SetTeams(r *teams.Registry) {
+    p.Teams = r
+}
+
+func (p *Parser) SetMarkets(c *markets.Classifier) {
+    p.Markets = c
 }
 
-type Selector struct {
-    TeamName string
-    MatchDate string
-    MatchTournament string
-    BetTitle string
-    BetDiv string
-    BetOpt string
-    BetCoef string
+func (p *Parser) SetBrowsers(pool *browser.Pool) {
+    p.Browsers = pool
 }
 
+func (p *Parser) SetPageTimeout(d time.Duration) {
+    p.PageTimeout = d
+}
+
+func (p *Parser) SetArchive(a *archive.Archive) {
+    p.Archive = a
+}
+
+func (p *Parser) SetClock(now func() time.Time) {
+    p.Clock = now
+}
+
+func (p *Parser) now() time.Time {
+    if p.Clock == nil {
+        return time.Now()
+    }
+
+    return p.Clock()
+}
+
+func (p *Parser) tab(ctx context.Context) (context.Context, func(), error) {
+    pool := p.Browsers
+    if pool == nil {
+        pool = browser.Default()
+    }
+
+    tab, release, err := pool.Tab(ctx)
+    if err != nil || p.PageTimeout <= 0 {
+        return tab, release, err
+    }
+
+    tab, cancel := context.WithTimeout(tab, p.PageTimeout)
+
+    return tab, func() {
+        cancel()
+        release()
+    }, nil
+}
+
+// writeCtx is used to store a page that was already loaded and parsed, it
+// is not cancelled with ctx so that shutting down does not drop the page
+func (p *Parser) writeCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+    return context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+}
+
+// keep archives the HTML a page was parsed from. It happens before parsing,
+// so pages a parser fails on are kept too, and a failure is only logged
+func (p *Parser) keep(kind string, source string, url string, html string) {
+    if p.Archive == nil {
+        return
+    }
+
+    _, err := p.Archive.Put(archive.Entry{
+        Kind: kind,
+        Source: source,
+        URL: url,
+        RunID: p.RunID,
+        ParserVersion: version(source),
+    }, html)
+    if err != nil {
+        logs.Errorf("Could not archive %s: %s", url, err.Error())
+    }
+}
+
+func (p *Parser) classify(bet *domain.Bet) {
+    if p.Markets == nil {
+        markets.Default().Apply(bet)
+        return
+    }
+
+    p.Markets.Apply(bet)
+}
+
+func (p *Parser) team(name, source string) string {
+    if p.Teams == nil {
+        return strings.TrimSpace(name)
+    }
+
+    return p.Teams.Resolve(name, source)
+}
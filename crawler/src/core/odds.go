@@ -0,0 +1,32 @@
+package core
+
+import (
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/odds"
+)
+
+// newOption builds an option from the name and coefficient scraped from the
+// page. Values that can not be parsed are kept as is without a price
+func newOption(name, value, source string, captured time.Time) domain.Option {
+    option := domain.Option{
+        Name: strings.TrimSpace(name),
+        Value: strings.TrimSpace(value),
+        Source: source,
+        CapturedAt: captured,
+    }
+
+    price, err := odds.Parse(option.Value)
+    if err != nil {
+        logs.Infof("Skipping price of %s: %s", option.Name, err.Error())
+        return option
+    }
+
+    option.Price = price.Decimal
+    option.Suspended = price.Suspended
+
+    return option
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"mxshs/crawler/src/retry"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// absURL resolves match urls found on listing pages, which are relative to
+// the site, absolute urls are returned as they are
+func absURL(base string, ref string) string {
+    u, err := neturl.Parse(base)
+    if err != nil {
+        return base + ref
+    }
+
+    r, err := u.Parse(ref)
+    if err != nil {
+        return base + ref
+    }
+
+    return r.String()
+}
+
+// navigate loads url in the tab. Failed navigations and responses sent by
+// bot protection or geo restrictions are returned as classified errors, so
+// only the ones worth retrying are retried
+func navigate(page context.Context, url string) error {
+    resp, err := chromedp.RunResponse(page, chromedp.Navigate(url))
+    if err != nil {
+        return retry.Wrap(retry.ClassOf(err), err)
+    }
+
+    if resp == nil {
+        return nil
+    }
+
+    return responseErr(url, resp.Status, resp.Headers)
+}
+
+// responseErr classifies the response to a navigation. Rate limiting (429)
+// passes, so it is retried with backoff like a network error
+func responseErr(url string, status int64, headers network.Headers) error {
+    switch {
+    case status == 451:
+        return retry.Errorf(retry.GeoBlocked, "[ERROR] %s is not available in this region (HTTP 451)\n", url)
+    case status == 429:
+        return retry.Errorf(retry.Network, "[ERROR] %s is rate limiting the crawler (HTTP 429)\n", url)
+    case status == 403 || hasHeader(headers, "cf-mitigated"):
+        return retry.Errorf(retry.Blocked, "[ERROR] %s blocked the crawler (HTTP %d)\n", url, status)
+    case status >= 500:
+        return retry.Errorf(retry.Network, "[ERROR] %s responded with HTTP %d\n", url, status)
+    }
+
+    return nil
+}
+
+// hasHeader looks name up in headers ignoring case, Chrome reports them as
+// the server sent them
+func hasHeader(headers network.Headers, name string) bool {
+    for h := range headers {
+        if strings.EqualFold(h, name) {
+            return true
+        }
+    }
+
+    return false
+}
+
+// waitErr classifies a failure after the page was loaded, running out of
+// time here means the element never appeared rather than a slow network
+func waitErr(err error) error {
+    if errors.Is(err, context.DeadlineExceeded) {
+        return retry.Wrap(
+            retry.SelectorNotFound,
+            fmt.Errorf("[ERROR] Page loaded but the expected content did not appear (possibly HTML changed): %w\n", err),
+        )
+    }
+
+    return retry.Wrap(retry.ClassOf(err), err)
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"testing"
+
+	"mxshs/crawler/src/retry"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestResponseErr(t *testing.T) {
+    cases := []struct {
+        name string
+        status int64
+        headers network.Headers
+        class retry.Class
+    }{
+        {"ok", 200, nil, ""},
+        {"not found", 404, nil, ""},
+        {"forbidden", 403, nil, retry.Blocked},
+        {"bot protection", 200, network.Headers{"cf-mitigated": "challenge"}, retry.Blocked},
+        {"bot protection as sent by Go", 200, network.Headers{"Cf-Mitigated": "challenge"}, retry.Blocked},
+        {"rate limited", 429, nil, retry.Network},
+        {"geo blocked", 451, nil, retry.GeoBlocked},
+        {"unavailable", 503, nil, retry.Network},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            err := responseErr("https://leon.ru/", c.status, c.headers)
+
+            if class := retry.ClassOf(err); class != c.class {
+                t.Errorf("got %q (%v), want %q", class, err, c.class)
+            }
+        })
+    }
+}
+
+func TestAbsURL(t *testing.T) {
+    cases := []struct {
+        base string
+        ref string
+        want string
+    }{
+        {"https://leon.ru", "/bets/1", "https://leon.ru/bets/1"},
+        {"https://leon.ru/bets/", "1", "https://leon.ru/bets/1"},
+        {"https://leon.ru", "https://www.leon.ru/bets/1", "https://www.leon.ru/bets/1"},
+    }
+
+    for _, c := range cases {
+        if got := absURL(c.base, c.ref); got != c.want {
+            t.Errorf("absURL(%q, %q) = %q, want %q", c.base, c.ref, got, c.want)
+        }
+    }
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/db"
+)
+
+// Capability is something a parser can do with the pages of its site
+type Capability string
+
+const (
+    // ParseMatchUrls collects match pages from a listing page
+    MatchList Capability = "match_list"
+    // ParseAll stores the odds of a match page
+    MatchOdds Capability = "match_odds"
+)
+
+// Registration describes a parser: the bookmaker it crawls, the hosts it
+// handles (subdomains included, the bookmaker's hosts when empty), what it
+// can do and how to build it
+type Registration struct {
+    Bookmaker bookmaker.Bookmaker
+    Hosts []string
+    Capabilities []Capability
+    // Version is stored with archived pages, bump it whenever what the
+    // parser extracts changes
+    Version string
+    New func(db db.Storage) BetParser
+}
+
+func (r Registration) Can(c Capability) bool {
+    for _, have := range r.Capabilities {
+        if have == c {
+            return true
+        }
+    }
+
+    return false
+}
+
+var (
+    registryMu sync.RWMutex
+    registry = map[string]Registration{}
+)
+
+// Register makes a parser available to Lookup, parsers register themselves
+// from init. Registering the same bookmaker twice replaces the parser
+func Register(r Registration) {
+    registryMu.Lock()
+    defer registryMu.Unlock()
+
+    registry[r.Bookmaker.Code] = r
+}
+
+// Registered lists every parser ordered by bookmaker code
+func Registered() []Registration {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+
+    res := make([]Registration, 0, len(registry))
+    for _, r := range registry {
+        res = append(res, current(r))
+    }
+
+    sort.Slice(res, func(i, j int) bool {
+        return res[i].Bookmaker.Code < res[j].Bookmaker.Code
+    })
+
+    return res
+}
+
+// Lookup finds the parser for the site serving rawURL
+func Lookup(rawURL string) (Registration, error) {
+    u, err := url.Parse(rawURL)
+    if err != nil || len(u.Hostname()) == 0 {
+        return Registration{}, fmt.Errorf("[ERROR] Not an absolute URL: %q\n", rawURL)
+    }
+
+    host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+    for _, r := range Registered() {
+        for _, h := range r.Hosts {
+            if host == h || strings.HasSuffix(host, "." + h) {
+                return r, nil
+            }
+        }
+    }
+
+    return Registration{}, fmt.Errorf("[ERROR] No parser for %s, supported sites: %s\n", host, strings.Join(hosts(), ", "))
+}
+
+// current fills in the bookmaker as it is now, its urls may have been
+// overridden after the parser registered (see bookmaker.Override)
+func current(r Registration) Registration {
+    if b, ok := bookmaker.ByCode(r.Bookmaker.Code); ok {
+        r.Bookmaker = b
+    }
+
+    if len(r.Hosts) == 0 {
+        r.Hosts = r.Bookmaker.Hosts
+    }
+
+    return r
+}
+
+// version of the parser registered for the bookmaker code
+func version(code string) string {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+
+    return registry[code].Version
+}
+
+func hosts() []string {
+    var res []string
+
+    for _, r := range Registered() {
+        res = append(res, r.Hosts...)
+    }
+
+    return res
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/db"
+
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed sites/*.yaml
+var builtinSites embed.FS
+
+// Site is the definition of a bookmaker site crawled by SiteParser, see
+// sites/leon.yaml for what the fields mean
+type Site struct {
+    Code string `yaml:"code"`
+    Name string `yaml:"name"`
+    BaseURL string `yaml:"base_url"`
+    Hosts []string `yaml:"hosts"`
+    Version string `yaml:"version"`
+    Listing Listing `yaml:"listing"`
+    Match Load `yaml:"match"`
+    Selector Selector `yaml:"selector"`
+    Dates Dates `yaml:"dates"`
+}
+
+// Load is how a page is loaded before its HTML is read
+type Load struct {
+    Wait string `yaml:"wait"`
+    Visible bool `yaml:"visible"`
+    Click string `yaml:"click"`
+    Settle time.Duration `yaml:"settle"`
+    Read string `yaml:"read"`
+}
+
+// Listing is how match pages are found on a listing page
+type Listing struct {
+    Load `yaml:",inline"`
+    Match string `yaml:"match"`
+    Link string `yaml:"link"`
+    Absolute bool `yaml:"absolute"`
+}
+
+// Selector locates the data of a match page, the bet selectors below
+// BetDiv are relative to a market and BetOpt, BetCoef to an outcome
+type Selector struct {
+    TeamName string `yaml:"team_name"`
+    ReverseTeams bool `yaml:"reverse_teams"`
+    MatchDate string `yaml:"match_date"`
+    MatchTournament string `yaml:"match_tournament"`
+    MatchTournamentIndex int `yaml:"match_tournament_index"`
+    BetDiv string `yaml:"bet_div"`
+    BetTitle string `yaml:"bet_title"`
+    BetOutcome string `yaml:"bet_outcome"`
+    BetOpt string `yaml:"bet_opt"`
+    BetCoef string `yaml:"bet_coef"`
+}
+
+// Dates is how the start of a match is parsed
+type Dates struct {
+    Layouts []string `yaml:"layouts"`
+    Months map[string]string `yaml:"months"`
+    Relative map[string]int `yaml:"relative"`
+    Missing string `yaml:"missing"`
+}
+
+// MissingNow dates a match page without a date at the time it is parsed,
+// live matches show none
+const MissingNow = "now"
+
+func init() {
+    files, err := builtinSites.ReadDir("sites")
+    if err != nil {
+        panic(err)
+    }
+
+    for _, f := range files {
+        name := path.Join("sites", f.Name())
+
+        data, err := builtinSites.ReadFile(name)
+        if err != nil {
+            panic(err)
+        }
+
+        err = addSite(name, data)
+        if err != nil {
+            panic(err)
+        }
+    }
+}
+
+// LoadSites registers the sites defined in the given files, a definition
+// replaces the parser of the site with the same code
+func LoadSites(paths ...string) error {
+    for _, p := range paths {
+        p = strings.TrimSpace(p)
+        if len(p) == 0 {
+            continue
+        }
+
+        data, err := os.ReadFile(p)
+        if err != nil {
+            return err
+        }
+
+        err = addSite(p, data)
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func addSite(name string, data []byte) error {
+    var site Site
+
+    dec := yaml.NewDecoder(bytes.NewReader(data))
+    dec.KnownFields(true)
+
+    err := dec.Decode(&site)
+    if err != nil {
+        return fmt.Errorf("[ERROR] Failed to read site definition %s: %s", name, err.Error())
+    }
+
+    err = site.validate()
+    if err != nil {
+        return fmt.Errorf("[ERROR] Invalid site definition %s: %s", name, err.Error())
+    }
+
+    return RegisterSite(site)
+}
+
+// RegisterSite makes the generic parser of a site available to Lookup and
+// defines its bookmaker
+func RegisterSite(site Site) error {
+    b := bookmaker.Bookmaker{
+        Code: site.Code,
+        Name: site.Name,
+        BaseURL: site.BaseURL,
+        Hosts: site.Hosts,
+    }
+
+    err := bookmaker.Define(b)
+    if err != nil {
+        return err
+    }
+
+    Register(Registration{
+        Bookmaker: b,
+        Capabilities: []Capability{MatchList, MatchOdds},
+        Version: site.Version,
+        New: func(db db.Storage) BetParser {
+            return GetSiteParser(site, db)
+        },
+    })
+
+    return nil
+}
+
+func (s *Site) validate() error {
+    if len(s.Code) == 0 {
+        return fmt.Errorf("code is required")
+    }
+
+    if len(s.Name) == 0 {
+        s.Name = s.Code
+    }
+
+    u, err := url.Parse(s.BaseURL)
+    if err != nil || len(u.Hostname()) == 0 {
+        return fmt.Errorf("base_url must be absolute, got %q", s.BaseURL)
+    }
+
+    if len(s.Hosts) == 0 {
+        s.Hosts = []string{strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")}
+    }
+
+    if s.Match.Settle <= 0 {
+        s.Match.Settle = time.Second
+    }
+
+    if s.Listing.Settle <= 0 {
+        s.Listing.Settle = time.Second
+    }
+
+    if len(s.Dates.Layouts) == 0 {
+        return fmt.Errorf("dates.layouts is required")
+    }
+
+    if len(s.Dates.Missing) > 0 && s.Dates.Missing != MissingNow {
+        return fmt.Errorf("dates.missing must be empty or %q, got %q", MissingNow, s.Dates.Missing)
+    }
+
+    selectors := []struct {
+        field string
+        value string
+        required bool
+    }{
+        {"listing.wait", s.Listing.Wait, true},
+        {"listing.click", s.Listing.Click, false},
+        {"listing.read", s.Listing.Read, true},
+        {"listing.match", s.Listing.Match, true},
+        {"listing.link", s.Listing.Link, false},
+        {"match.wait", s.Match.Wait, true},
+        {"match.click", s.Match.Click, false},
+        {"match.read", s.Match.Read, true},
+        {"selector.team_name", s.Selector.TeamName, true},
+        {"selector.match_date", s.Selector.MatchDate, true},
+        {"selector.match_tournament", s.Selector.MatchTournament, false},
+        {"selector.bet_div", s.Selector.BetDiv, true},
+        {"selector.bet_title", s.Selector.BetTitle, true},
+        {"selector.bet_outcome", s.Selector.BetOutcome, true},
+        {"selector.bet_opt", s.Selector.BetOpt, true},
+        {"selector.bet_coef", s.Selector.BetCoef, true},
+    }
+
+    for _, sel := range selectors {
+        if len(sel.value) == 0 {
+            if sel.required {
+                return fmt.Errorf("%s is required", sel.field)
+            }
+
+            continue
+        }
+
+        _, err := cascadia.ParseGroup(sel.value)
+        if err != nil {
+            return fmt.Errorf("%s: %s", sel.field, err.Error())
+        }
+    }
+
+    return nil
+}
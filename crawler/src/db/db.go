@@ -1,51 +1,49 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
 
+	"mxshs/crawler/src/bookmaker"
 	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/resolve"
+	"mxshs/crawler/src/teams"
 
-	"github.com/joho/godotenv"
 	pq "github.com/lib/pq"
 )
 
-var (
-    HOST string
-    PORT string
-    USER string
-    PASS string
-    DBNAME string
-)
+// Config of the database connection, Driver picks the storage: postgres
+// (default), sqlite (a file at Path) or memory
+type Config struct {
+    Driver string `yaml:"driver"`
+    Host string `yaml:"host"`
+    Port string `yaml:"port"`
+    User string `yaml:"user"`
+    Pass string `yaml:"pass"`
+    Name string `yaml:"name"`
+    Path string `yaml:"path"`
+}
 
 type DB struct {
     db *sql.DB
+    resolver *resolve.Resolver
 }
 
-func init() {
-    err := godotenv.Load(".env")
-    if err != nil {
-        panic("Could not locate .env file")
-    }
-
-    HOST, _ = os.LookupEnv("DB_HOST")
-    PORT, _ = os.LookupEnv("DB_PORT")
-    USER, _ = os.LookupEnv("DB_USER")
-    PASS, _ = os.LookupEnv("DB_PASS")
-    DBNAME, _ = os.LookupEnv("DB")
-}
+// Arbitrary key serializing event resolution so that two bookmakers saving
+// the same new match at once do not create two events for it
+const eventsLock = 7315902
 
-func GetDB() (*DB, error) {
+func GetDB(cfg Config) (*DB, error) {
     db := &DB{}
 
     conn_info := fmt.Sprintf(
         "host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-        HOST,
-        PORT,
-        USER,
-        PASS,
-        DBNAME,
+        cfg.Host,
+        cfg.Port,
+        cfg.User,
+        cfg.Pass,
+        cfg.Name,
     )
 
     conn, err := sql.Open("postgres", conn_info)
@@ -54,104 +52,236 @@ func GetDB() (*DB, error) {
     }
 
     db.db = conn
+    db.resolver = resolve.GetResolver()
 
     return db, nil
 }
 
-func (db *DB) InsertBet(game_id int, bet *domain.Bet) (int, error) {
-    var bet_id int
+func (db *DB) Migrate() error {
+    return db.migrator().Up()
+}
 
-    bet_arr := [][]string{}
+func (db *DB) MigrateDown(steps int) error {
+    return db.migrator().Down(steps)
+}
 
-    for _, opt := range bet.Opts {
-        bet_arr = append(bet_arr, []string{opt.Name, opt.Value})
-    }
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+    return db.migrator().Status()
+}
 
-    q, err := db.db.Query(
-        `INSERT INTO bets (type, bet, game_id)
-        VALUES ($1, $2, $3) RETURNING bet_id;`,
-        bet.Type,
-        pq.Array(bet_arr),
-        game_id,
-    )
-    if err != nil {
-        return bet_id, err
-    }
+func (db *DB) migrator() *migrator {
+    return &migrator{db: db.db, dialect: "postgres"}
+}
 
-    q.Next()
+func (db *DB) StartRun(ctx context.Context, source bookmaker.Bookmaker, start_url string) (int64, error) {
+    return startRun(ctx, db.db, source, start_url)
+}
 
-    err = q.Scan(&bet_id)
-    if err != nil {
-        return bet_id, err
-    }
+func (db *DB) FinishRun(ctx context.Context, run_id int64, stats RunStats, runErr error) error {
+    return finishRun(ctx, db.db, run_id, stats, runErr)
+}
 
-    err = q.Close()
+func (db *DB) LoadTeams() ([]teams.Team, error) {
+    return loadTeams(db.db)
+}
 
-    return bet_id, err
+func (db *DB) SaveUnknownTeams(unknown []teams.Suggestion) error {
+    return saveUnknownTeams(db.db, unknown)
 }
 
-func (db *DB) InsertGame(game *domain.GameBets) (int, error) {
+func (db *DB) PendingTeams() ([]teams.Suggestion, error) {
+    return pendingTeams(db.db)
+}
+
+func (db *DB) AddTeam(name string) error {
+    return addTeamTx(db.db, name)
+}
+
+func (db *DB) ApproveAlias(alias, team string) error {
+    return approveAlias(db.db, alias, team)
+}
+
+func (db *DB) Close() error {
+    return db.db.Close()
+}
+
+// SaveGameBets persists the game together with all of its bets in a single
+// transaction and returns the game_id. Options are staged with COPY, markets
+// and outcomes are upserted from the staging table and a snapshot is only
+// kept when its value differs from the latest one stored for the outcome, so
+// re-crawling an unchanged line adds nothing.
+func (db *DB) SaveGameBets(ctx context.Context, game *domain.GameBets) (int, error) {
+    return db.save(ctx, game, false)
+}
+
+func (db *DB) ReplayGameBets(ctx context.Context, game *domain.GameBets) (int, error) {
+    return db.save(ctx, game, true)
+}
+
+func (db *DB) save(ctx context.Context, game *domain.GameBets, replay bool) (int, error) {
     var game_id int
 
-    game_id, err := db.checkIfGamePresent(game)
+    tx, err := db.db.BeginTx(ctx, nil)
     if err != nil {
         return game_id, err
     }
+    defer tx.Rollback()
 
-    if game_id == -1 {
+    err = tx.QueryRowContext(
+        ctx,
+        upsertGameSQL(replay),
+        game.Date,
+        game.Tournament,
+        game.TeamA,
+        game.TeamB,
+        game.Source,
+        game.SourceURL,
+        runID(game.RunID),
+        crawledAt(game, replay),
+    ).Scan(&game_id)
+    if err != nil {
+        return game_id, err
+    }
 
-        q, err := db.db.Query(
-            `INSERT INTO games (date, tournament, radiant, dire) 
-            VALUES ($1, $2, $3, $4) RETURNING game_id;`,
-            game.Date,
-            game.Tournament,
-            game.TeamA,
-            game.TeamB,
-        )
-        if err != nil {
-            return game_id, err
-        }
+    _, err = tx.ExecContext(
+        ctx,
+        `CREATE TEMP TABLE staged_snapshots (
+            market varchar(250),
+            kind varchar(50),
+            map_no integer,
+            line numeric(6, 2),
+            outcome varchar(250),
+            ordinal integer,
+            price numeric(10, 3),
+            suspended boolean,
+            raw_value varchar(50),
+            source varchar(100),
+            captured_at timestamp with time zone
+        ) ON COMMIT DROP;`,
+    )
+    if err != nil {
+        return game_id, err
+    }
 
-        q.Next()
+    stmt, err := tx.PrepareContext(ctx, pq.CopyIn(
+        "staged_snapshots",
+        "market", "kind", "map_no", "line", "outcome", "ordinal", "price", "suspended", "raw_value", "source", "captured_at",
+    ))
+    if err != nil {
+        return game_id, err
+    }
 
-        err = q.Scan(&game_id)
-        if err != nil {
-            return game_id, err
+    for _, bet := range game.Bets {
+        kind, map_no, line := marketKind(bet)
+
+        for i, opt := range bet.Opts {
+            _, err = stmt.ExecContext(
+                ctx,
+                bet.Type,
+                kind,
+                map_no,
+                line,
+                opt.Name,
+                i,
+                optionPrice(opt),
+                opt.Suspended,
+                opt.Value,
+                opt.Source,
+                opt.CapturedAt,
+            )
+            if err != nil {
+                stmt.Close()
+                return game_id, err
+            }
         }
-
-        err = q.Close()
     }
 
-    return game_id, err 
-}
-
-func (db *DB) checkIfGamePresent(game *domain.GameBets) (int, error) {
-    var game_id int
+    _, err = stmt.ExecContext(ctx)
+    if err != nil {
+        stmt.Close()
+        return game_id, err
+    }
 
-    q, err := db.db.Query(
-        `SELECT game_id FROM games WHERE date=$1 and radiant=$2;`,
-        game.Date,
-        game.TeamA,
-    )
+    err = stmt.Close()
     if err != nil {
-        return -1, err
+        return game_id, err
     }
 
-    present := q.Next()
-    if !present {
-        return -1, nil
+    _, err = tx.ExecContext(
+        ctx,
+        `INSERT INTO markets (game_id, name, kind, map_no, line)
+        SELECT DISTINCT ON (market) $1::integer, market, kind, map_no, line
+        FROM staged_snapshots
+        ON CONFLICT (game_id, name) DO UPDATE
+        SET kind=EXCLUDED.kind, map_no=EXCLUDED.map_no, line=EXCLUDED.line;`,
+        game_id,
+    )
+    if err != nil {
+        return game_id, err
     }
 
-    err = q.Scan(&game_id)
+    _, err = tx.ExecContext(
+        ctx,
+        `INSERT INTO outcomes (market_id, label, ordinal)
+        SELECT DISTINCT ON (m.market_id, s.outcome) m.market_id, s.outcome, s.ordinal
+        FROM staged_snapshots s
+        JOIN markets m ON m.game_id=$1 AND m.name=s.market
+        ORDER BY m.market_id, s.outcome, s.ordinal
+        ON CONFLICT (market_id, label) DO UPDATE SET ordinal=EXCLUDED.ordinal;`,
+        game_id,
+    )
     if err != nil {
-        return -1, err
+        return game_id, err
     }
 
-    fmt.Printf(
-        "[INFO] Match data is already parsed - game_id=%d (still proceeding with bets for the match)\n",
+    _, err = tx.ExecContext(
+        ctx,
+        `INSERT INTO odds_snapshots (outcome_id, price, suspended, raw_value, source, captured_at, run_id)
+        SELECT o.outcome_id, s.price, s.suspended, s.raw_value, s.source, s.captured_at, $2
+        FROM staged_snapshots s
+        JOIN markets m ON m.game_id=$1 AND m.name=s.market
+        JOIN outcomes o ON o.market_id=m.market_id AND o.label=s.outcome
+        WHERE s.raw_value IS DISTINCT FROM (
+            SELECT l.raw_value FROM odds_snapshots l
+            WHERE l.outcome_id=o.outcome_id AND l.captured_at <= s.captured_at
+            ORDER BY l.captured_at DESC LIMIT 1
+        )
+        ON CONFLICT (outcome_id, captured_at) DO NOTHING;`,
         game_id,
+        runID(game.RunID),
     )
+    if err != nil {
+        return game_id, err
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return game_id, err
+    }
 
-    return game_id, nil
+    return game_id, db.link(ctx, game_id, game)
 }
 
+// link resolves the event of a saved game in a short transaction of its own,
+// so the global eventsLock is only held while candidates are compared and
+// not through the writes of the odds. A game left unlinked by a failure here
+// is linked when the page is saved again
+func (db *DB) link(ctx context.Context, game_id int, game *domain.GameBets) error {
+    tx, err := db.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1);`, eventsLock)
+    if err != nil {
+        return err
+    }
+
+    err = linkEvent(ctx, tx, db.resolver, game_id, game)
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/domain"
+)
+
+// storages returns a fresh instance of every storage that runs without a
+// server, the tests run against each of them
+func storages(t *testing.T) map[string]Storage {
+    t.Helper()
+
+    sqlite, err := GetSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    err = sqlite.Migrate()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    t.Cleanup(func() {
+        sqlite.Close()
+    })
+
+    return map[string]Storage{
+        "memory": GetMemoryDB(),
+        "sqlite": sqlite,
+    }
+}
+
+var matchStart = time.Date(2026, time.March, 14, 18, 30, 0, 0, time.UTC)
+
+// testGame is a game of source with a single market priced at value
+func testGame(source string, teamA string, value string, captured time.Time) *domain.GameBets {
+    return &domain.GameBets{
+        TeamA: teamA,
+        TeamB: "OG",
+        Date: matchStart,
+        Tournament: "The International 2026",
+        Source: source,
+        SourceURL: "https://leon.ru/bets/esports/dota2/1-" + source,
+        Bets: []domain.Bet{{
+            Type: "Победитель",
+            Opts: []domain.Option{{Name: "1", Value: value, Price: 1.5, Source: source, CapturedAt: captured}},
+        }},
+    }
+}
+
+// runOf is the run_id the game of source is stored under
+func runOf(t *testing.T, store Storage, source string) int64 {
+    t.Helper()
+
+    switch s := store.(type) {
+    case *MemoryDB:
+        for _, g := range s.Games() {
+            if g.Source == source {
+                return g.RunID
+            }
+        }
+    case *SQLiteDB:
+        var run_id sql.NullInt64
+
+        err := s.db.QueryRow(`SELECT run_id FROM games WHERE source=$1;`, source).Scan(&run_id)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        return run_id.Int64
+    }
+
+    t.Fatalf("no game of %s", source)
+    return 0
+}
+
+// replaying archived pages over stored games leaves the run and crawl time
+// of the games alone, and odds are compared with what was current when the
+// page was captured
+func TestSaveSameCaptureTime(t *testing.T) {
+    ctx := context.Background()
+
+    captured := matchStart.Add(-time.Hour)
+
+    for name, store := range storages(t) {
+        t.Run(name, func(t *testing.T) {
+            // the second price captured at the same time is dropped, so the
+            // price a minute later is no change
+            saves := []struct {
+                value string
+                at time.Time
+            }{
+                {"1.50", captured},
+                {"1.70", captured},
+                {"1.50", captured.Add(time.Minute)},
+            }
+
+            for _, save := range saves {
+                _, err := store.SaveGameBets(ctx, testGame("leon", "Team Spirit", save.value, save.at))
+                if err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            records, err := store.(Reader).ListSnapshots(ctx, Filter{Source: "leon"})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            var values []string
+            for _, r := range records {
+                values = append(values, r.Value)
+            }
+
+            if len(values) != 1 || values[0] != "1.50" {
+                t.Errorf("snapshots %v, want 1.50", values)
+            }
+        })
+    }
+}
+
+func TestReplayGameBets(t *testing.T) {
+    ctx := context.Background()
+
+    for name, store := range storages(t) {
+        t.Run(name, func(t *testing.T) {
+            replayer, ok := store.(Replayer)
+            if !ok {
+                t.Fatal("storage can not replay")
+            }
+
+            run_id, err := store.StartRun(ctx, bookmaker.Bookmaker{Code: "leon"}, "https://leon.ru/")
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            first := matchStart.Add(-3 * time.Hour)
+
+            for i, value := range []string{"1.50", "1.70"} {
+                game := testGame("leon", "Team Spirit", value, first.Add(time.Duration(i) * time.Hour))
+                game.RunID = run_id
+
+                _, err = store.SaveGameBets(ctx, game)
+                if err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            url := testGame("leon", "", "", first).SourceURL
+
+            states, err := store.MatchStates(ctx, "leon", []string{url})
+            if err != nil {
+                t.Fatal(err)
+            }
+            crawled := states[url].CrawledAt
+
+            // 1.50 was the price half an hour after the first crawl, 1.90
+            // is news
+            for _, value := range []string{"1.50", "1.90"} {
+                _, err = replayer.ReplayGameBets(ctx, testGame("leon", "Team Spirit", value, first.Add(30 * time.Minute)))
+                if err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            records, err := store.(Reader).ListSnapshots(ctx, Filter{Source: "leon"})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            var values []string
+            for _, r := range records {
+                values = append(values, r.Value)
+            }
+
+            if len(values) != 3 || values[0] != "1.50" || values[1] != "1.90" || values[2] != "1.70" {
+                t.Errorf("snapshots %v, want 1.50 1.90 1.70", values)
+            }
+
+            states, err = store.MatchStates(ctx, "leon", []string{url})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if !states[url].CrawledAt.Equal(crawled) {
+                t.Errorf("crawled at %s after the replay, want %s", states[url].CrawledAt, crawled)
+            }
+
+            if got := runOf(t, store, "leon"); got != run_id {
+                t.Errorf("game stored under run %d after the replay, want %d", got, run_id)
+            }
+
+            // a game only found in the archive was crawled when it was captured
+            _, err = replayer.ReplayGameBets(ctx, testGame("ggbet", "Team Spirit", "1.50", first))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            url = testGame("ggbet", "", "", first).SourceURL
+
+            states, err = store.MatchStates(ctx, "ggbet", []string{url})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if !states[url].CrawledAt.Equal(first) {
+                t.Errorf("replayed game crawled at %s, want %s", states[url].CrawledAt, first)
+            }
+        })
+    }
+}
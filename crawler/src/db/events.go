@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/resolve"
+)
+
+func fixture(game *domain.GameBets) resolve.Fixture {
+    return resolve.Fixture{
+        TeamA: game.TeamA,
+        TeamB: game.TeamB,
+        StartsAt: game.Date.UTC(),
+        Tournament: game.Tournament,
+    }
+}
+
+// linkEvent attaches a stored game to the canonical event of the match,
+// creating the event when no other bookmaker has listed the match yet.
+// Events that already have a game from the same bookmaker are not considered
+func linkEvent(ctx context.Context, tx *sql.Tx, r *resolve.Resolver, game_id int, game *domain.GameBets) error {
+    var event_id sql.NullInt64
+
+    err := tx.QueryRowContext(ctx, `SELECT event_id FROM games WHERE game_id=$1;`, game_id).Scan(&event_id)
+    if err != nil || event_id.Valid {
+        return err
+    }
+
+    f := fixture(game)
+
+    rows, err := tx.QueryContext(
+        ctx,
+        `SELECT e.event_id, e.team_a, e.team_b, e.starts_at, coalesce(e.tournament, '')
+        FROM events e
+        WHERE e.starts_at BETWEEN $1 AND $2
+        AND NOT EXISTS (
+            SELECT 1 FROM games g WHERE g.event_id=e.event_id AND g.source=$3
+        );`,
+        f.StartsAt.Add(-r.Window),
+        f.StartsAt.Add(r.Window),
+        game.Source,
+    )
+    if err != nil {
+        return err
+    }
+
+    var candidates []resolve.Fixture
+
+    for rows.Next() {
+        c := resolve.Fixture{}
+
+        err = rows.Scan(&c.ID, &c.TeamA, &c.TeamB, &c.StartsAt, &c.Tournament)
+        if err != nil {
+            rows.Close()
+            return err
+        }
+
+        candidates = append(candidates, c)
+    }
+
+    rows.Close()
+    if err = rows.Err(); err != nil {
+        return err
+    }
+
+    match, ok := r.Match(f, candidates)
+    if ok {
+        event_id.Int64 = match.ID
+    } else {
+        err = tx.QueryRowContext(
+            ctx,
+            `INSERT INTO events (team_a, team_b, starts_at, tournament)
+            VALUES ($1, $2, $3, $4) RETURNING event_id;`,
+            f.TeamA,
+            f.TeamB,
+            f.StartsAt,
+            f.Tournament,
+        ).Scan(&event_id.Int64)
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err = tx.ExecContext(ctx, `UPDATE games SET event_id=$1 WHERE game_id=$2;`, event_id.Int64, game_id)
+
+    return err
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"mxshs/crawler/src/domain"
+)
+
+// JSONLSink writes every saved game with all of its bets as one JSON line,
+// runs, teams and change detection are kept in memory for the process
+type JSONLSink struct {
+    *MemoryDB
+    mu sync.Mutex
+    enc *json.Encoder
+    closer io.Closer
+}
+
+// GetJSONLSink writes to the file at path, or to stdout when path is "-"
+func GetJSONLSink(path string) (*JSONLSink, error) {
+    if path == "-" {
+        return &JSONLSink{MemoryDB: GetMemoryDB(), enc: json.NewEncoder(os.Stdout)}, nil
+    }
+
+    f, err := os.OpenFile(path, os.O_CREATE | os.O_WRONLY | os.O_APPEND, 0644)
+    if err != nil {
+        return nil, err
+    }
+
+    return &JSONLSink{MemoryDB: GetMemoryDB(), enc: json.NewEncoder(f), closer: f}, nil
+}
+
+func (s *JSONLSink) SaveGameBets(ctx context.Context, game *domain.GameBets) (int, error) {
+    game_id, err := s.MemoryDB.SaveGameBets(ctx, game)
+    if err != nil {
+        return game_id, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return game_id, s.enc.Encode(game)
+}
+
+func (s *JSONLSink) Close() error {
+    if s.closer == nil {
+        return nil
+    }
+
+    return s.closer.Close()
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MatchState is what is known about the page of a match from earlier crawls
+type MatchState struct {
+    Date time.Time
+    CrawledAt time.Time
+}
+
+// matchStates is shared by the SQL storages. When a page was reused for
+// another game the latest one wins
+func matchStates(ctx context.Context, conn *sql.DB, source string, urls []string) (map[string]MatchState, error) {
+    res := map[string]MatchState{}
+
+    if len(urls) == 0 {
+        return res, nil
+    }
+
+    args := []any{source}
+    marks := make([]string, len(urls))
+
+    for i, url := range urls {
+        args = append(args, url)
+        marks[i] = fmt.Sprintf("$%d", i + 2)
+    }
+
+    rows, err := conn.QueryContext(
+        ctx,
+        `SELECT source_url, date, crawled_at FROM games
+        WHERE source=$1 AND source_url IN (` + strings.Join(marks, ", ") + `) AND date IS NOT NULL
+        ORDER BY date;`,
+        args...,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var url string
+        var state MatchState
+        var crawled sql.NullTime
+
+        err = rows.Scan(&url, &state.Date, &crawled)
+        if err != nil {
+            return nil, err
+        }
+
+        state.CrawledAt = crawled.Time
+        res[url] = state
+    }
+
+    return res, rows.Err()
+}
+
+func (db *DB) MatchStates(ctx context.Context, source string, urls []string) (map[string]MatchState, error) {
+    return matchStates(ctx, db.db, source, urls)
+}
+
+func (db *SQLiteDB) MatchStates(ctx context.Context, source string, urls []string) (map[string]MatchState, error) {
+    return matchStates(ctx, db.db, source, urls)
+}
+
+func (db *MemoryDB) MatchStates(ctx context.Context, source string, urls []string) (map[string]MatchState, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    listed := map[string]bool{}
+    for _, url := range urls {
+        listed[url] = true
+    }
+
+    res := map[string]MatchState{}
+
+    for i, g := range db.games {
+        if g.Source != source || !listed[g.SourceURL] {
+            continue
+        }
+
+        if state, ok := res[g.SourceURL]; ok && state.Date.After(g.Date) {
+            continue
+        }
+
+        res[g.SourceURL] = MatchState{Date: g.Date, CrawledAt: db.crawled[i]}
+    }
+
+    return res, nil
+}
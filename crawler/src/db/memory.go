@@ -0,0 +1,371 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/resolve"
+	"mxshs/crawler/src/teams"
+)
+
+// MemoryDB keeps everything in process memory. Useful for local runs without
+// Postgres and for tests
+type MemoryDB struct {
+    mu sync.Mutex
+    games []domain.GameBets
+    snapshots []Snapshot
+    runs []Run
+    events []resolve.Fixture
+    // event id of every game, indexed like games
+    gameEvents []int64
+    // when every game was crawled last, indexed like games
+    crawled []time.Time
+    jobs []Job
+    // when every job changed state last, indexed like jobs
+    jobUpdated []time.Time
+    lastJob int64
+    resolver *resolve.Resolver
+    teams []teams.Team
+    pending map[string]teams.Suggestion
+}
+
+func GetMemoryDB() *MemoryDB {
+    return &MemoryDB{
+        resolver: resolve.GetResolver(),
+        pending: map[string]teams.Suggestion{},
+    }
+}
+
+func (db *MemoryDB) Migrate() error {
+    return nil
+}
+
+func (db *MemoryDB) StartRun(ctx context.Context, source bookmaker.Bookmaker, start_url string) (int64, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    db.runs = append(db.runs, Run{
+        ID: int64(len(db.runs) + 1),
+        Source: source.Code,
+        StartURL: start_url,
+        StartedAt: time.Now().UTC(),
+        Status: RunRunning,
+    })
+
+    return int64(len(db.runs)), nil
+}
+
+func (db *MemoryDB) FinishRun(ctx context.Context, run_id int64, stats RunStats, runErr error) error {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    if run_id < 1 || int(run_id) > len(db.runs) {
+        return fmt.Errorf("[ERROR] Unknown run: %d", run_id)
+    }
+
+    run := &db.runs[run_id - 1]
+    run.FinishedAt = time.Now().UTC()
+    run.Status = RunStatus(runErr)
+    run.Stats = stats
+
+    if runErr != nil {
+        run.Error = runErr.Error()
+    }
+
+    return nil
+}
+
+func (db *MemoryDB) Runs() []Run {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    return append([]Run{}, db.runs...)
+}
+
+func (db *MemoryDB) LoadTeams() ([]teams.Team, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    res := make([]teams.Team, len(db.teams))
+
+    for i, t := range db.teams {
+        res[i] = teams.Team{Name: t.Name, Aliases: append([]string{}, t.Aliases...)}
+    }
+
+    return res, nil
+}
+
+func (db *MemoryDB) SaveUnknownTeams(unknown []teams.Suggestion) error {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    for _, s := range unknown {
+        db.pending[s.Alias] = s
+    }
+
+    return nil
+}
+
+func (db *MemoryDB) PendingTeams() ([]teams.Suggestion, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    var res []teams.Suggestion
+
+    for _, s := range db.pending {
+        res = append(res, s)
+    }
+
+    sort.Slice(res, func(i, j int) bool { return res[i].Alias < res[j].Alias })
+
+    return res, nil
+}
+
+func (db *MemoryDB) AddTeam(name string) error {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    db.addTeam(name)
+
+    return nil
+}
+
+func (db *MemoryDB) ApproveAlias(alias, team string) error {
+    if len(alias) == 0 || len(team) == 0 {
+        return fmt.Errorf("[ERROR] Both alias and team are required")
+    }
+
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    t := db.addTeam(team)
+    t.Aliases = append(t.Aliases, alias)
+    delete(db.pending, alias)
+
+    if alias != team {
+        db.renameTeam(alias, team)
+    }
+
+    return nil
+}
+
+// renameTeam is the counterpart of the SQL renameTeam
+func (db *MemoryDB) renameTeam(alias, team string) {
+    for i := range db.games {
+        g := &db.games[i]
+
+        if g.TeamA == alias && db.findGame(g.Source, team, g.Date) == 0 {
+            g.TeamA = team
+        }
+
+        if g.TeamB == alias {
+            g.TeamB = team
+        }
+    }
+
+    for i := range db.events {
+        if db.events[i].TeamA == alias {
+            db.events[i].TeamA = team
+        }
+
+        if db.events[i].TeamB == alias {
+            db.events[i].TeamB = team
+        }
+    }
+}
+
+// findGame returns the id of the game stored under the games key, 0 when
+// there is none
+func (db *MemoryDB) findGame(source string, radiant string, date time.Time) int {
+    for i, stored := range db.games {
+        if stored.Source == source && stored.Date.Equal(date) && stored.TeamA == radiant {
+            return i + 1
+        }
+    }
+
+    return 0
+}
+
+func (db *MemoryDB) addTeam(name string) *teams.Team {
+    delete(db.pending, name)
+
+    for i := range db.teams {
+        if db.teams[i].Name == name {
+            return &db.teams[i]
+        }
+    }
+
+    db.teams = append(db.teams, teams.Team{Name: name})
+
+    return &db.teams[len(db.teams) - 1]
+}
+
+func (db *MemoryDB) Close() error {
+    return nil
+}
+
+func (db *MemoryDB) SaveGameBets(ctx context.Context, game *domain.GameBets) (int, error) {
+    return db.save(ctx, game, false)
+}
+
+func (db *MemoryDB) ReplayGameBets(ctx context.Context, game *domain.GameBets) (int, error) {
+    return db.save(ctx, game, true)
+}
+
+func (db *MemoryDB) save(ctx context.Context, game *domain.GameBets, replay bool) (int, error) {
+    if err := ctx.Err(); err != nil {
+        return 0, err
+    }
+
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    game_id := db.upsertGame(game, replay)
+    db.linkEvent(game_id, game)
+
+    for _, bet := range game.Bets {
+        kind, _, _ := marketKind(bet)
+
+        for i, opt := range bet.Opts {
+            // one snapshot per outcome and capture time, like the unique
+            // (outcome_id, captured_at) of the SQL storages
+            last, ok := db.latest(game_id, bet.Type, opt.Name, opt.CapturedAt)
+            if ok && (last.Value == opt.Value || last.CapturedAt.Equal(opt.CapturedAt)) {
+                continue
+            }
+
+            db.snapshots = append(db.snapshots, Snapshot{
+                GameID: game_id,
+                Market: bet.Type,
+                Kind: kind,
+                Outcome: opt.Name,
+                Ordinal: i,
+                Price: optionPrice(opt),
+                Suspended: opt.Suspended,
+                Value: opt.Value,
+                Source: opt.Source,
+                RunID: game.RunID,
+                CapturedAt: opt.CapturedAt,
+            })
+        }
+    }
+
+    return game_id, nil
+}
+
+// upsertGame stores game under its (source, radiant, date) key, a replayed
+// game keeps the run and crawl time of the stored one
+func (db *MemoryDB) upsertGame(game *domain.GameBets, replay bool) int {
+    g := *game
+    g.Bets = nil
+
+    if game_id := db.findGame(game.Source, game.TeamA, game.Date); game_id > 0 {
+        if replay {
+            g.RunID = db.games[game_id - 1].RunID
+        } else {
+            db.crawled[game_id - 1] = time.Now()
+        }
+
+        db.games[game_id - 1] = g
+        return game_id
+    }
+
+    db.games = append(db.games, g)
+    db.gameEvents = append(db.gameEvents, 0)
+    db.crawled = append(db.crawled, crawledAt(game, replay))
+
+    return len(db.games)
+}
+
+func (db *MemoryDB) linkEvent(game_id int, game *domain.GameBets) {
+    if db.gameEvents[game_id - 1] != 0 {
+        return
+    }
+
+    f := fixture(game)
+
+    var candidates []resolve.Fixture
+
+    for _, e := range db.events {
+        taken := false
+
+        for i, g := range db.games {
+            if db.gameEvents[i] == e.ID && g.Source == game.Source {
+                taken = true
+                break
+            }
+        }
+
+        if !taken {
+            candidates = append(candidates, e)
+        }
+    }
+
+    match, ok := db.resolver.Match(f, candidates)
+    if !ok {
+        f.ID = int64(len(db.events) + 1)
+        db.events = append(db.events, f)
+        match = f
+    }
+
+    db.gameEvents[game_id - 1] = match.ID
+}
+
+// EventOf returns the event id the game is linked to
+func (db *MemoryDB) EventOf(game_id int) int64 {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    if game_id < 1 || game_id > len(db.gameEvents) {
+        return 0
+    }
+
+    return db.gameEvents[game_id - 1]
+}
+
+// Games returns a copy of the stored games, game_id is the index + 1
+func (db *MemoryDB) Games() []domain.GameBets {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    return append([]domain.GameBets{}, db.games...)
+}
+
+func (db *MemoryDB) Snapshots(game_id int) []Snapshot {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    var res []Snapshot
+
+    for _, s := range db.snapshots {
+        if s.GameID == game_id {
+            res = append(res, s)
+        }
+    }
+
+    return res
+}
+
+// latest snapshot of an outcome captured at or before at
+func (db *MemoryDB) latest(game_id int, market, outcome string, at time.Time) (Snapshot, bool) {
+    var last Snapshot
+    found := false
+
+    for _, s := range db.snapshots {
+        if s.GameID != game_id || s.Market != market || s.Outcome != outcome || s.CapturedAt.After(at) {
+            continue
+        }
+
+        if !found || !s.CapturedAt.Before(last.CapturedAt) {
+            last = s
+            found = true
+        }
+    }
+
+    return last, found
+}
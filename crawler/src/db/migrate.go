@@ -0,0 +1,364 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/logs"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+// Arbitrary key for pg_advisory_lock so that two crawlers starting at the same
+// time do not apply migrations concurrently
+const migrationLock = 7315901
+
+type Migration struct {
+    Version int
+    Name string
+    Up string
+    Down string
+    Checksum string
+}
+
+type MigrationStatus struct {
+    Migration
+    Applied bool
+    AppliedAt time.Time
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+    dir := path.Join("migrations", dialect)
+
+    entries, err := fs.ReadDir(migrationsFS, dir)
+    if err != nil {
+        return nil, err
+    }
+
+    byVersion := map[int]*Migration{}
+
+    for _, e := range entries {
+        // files are named <version>_<name>.<up|down>.sql
+        name := e.Name()
+        if !strings.HasSuffix(name, ".sql") {
+            continue
+        }
+
+        base := strings.TrimSuffix(name, ".sql")
+        direction := path.Ext(base)
+        base = strings.TrimSuffix(base, direction)
+
+        version, label, ok := strings.Cut(base, "_")
+        if !ok {
+            return nil, fmt.Errorf("[ERROR] Malformed migration file name: %s", name)
+        }
+
+        v, err := strconv.Atoi(version)
+        if err != nil {
+            return nil, fmt.Errorf("[ERROR] Malformed migration version in %s: %s", name, err.Error())
+        }
+
+        body, err := fs.ReadFile(migrationsFS, path.Join(dir, name))
+        if err != nil {
+            return nil, err
+        }
+
+        m, ok := byVersion[v]
+        if !ok {
+            m = &Migration{Version: v, Name: label}
+            byVersion[v] = m
+        }
+
+        switch direction {
+        case ".up":
+            m.Up = string(body)
+            sum := sha256.Sum256(body)
+            m.Checksum = hex.EncodeToString(sum[:])
+        case ".down":
+            m.Down = string(body)
+        default:
+            return nil, fmt.Errorf("[ERROR] Unknown migration direction in %s", name)
+        }
+    }
+
+    var res []Migration
+
+    for _, m := range byVersion {
+        if len(m.Up) == 0 {
+            return nil, fmt.Errorf("[ERROR] Migration %d has no up script", m.Version)
+        }
+
+        res = append(res, *m)
+    }
+
+    sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+
+    return res, nil
+}
+
+// migrator applies the embedded migrations of one dialect to a connection
+type migrator struct {
+    db *sql.DB
+    dialect string
+}
+
+func (m *migrator) withConn(f func(conn *sql.Conn, migrations []Migration) error) error {
+    migrations, err := loadMigrations(m.dialect)
+    if err != nil {
+        return err
+    }
+
+    ctx := context.Background()
+
+    conn, err := m.db.Conn(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    switch m.dialect {
+    case "postgres":
+        _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLock)
+        if err != nil {
+            return err
+        }
+        defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, migrationLock)
+    case "sqlite":
+        // Rebuilding a referenced table (the only way to change constraints in
+        // sqlite) requires foreign keys off, they are checked after each script
+        _, err = conn.ExecContext(ctx, `PRAGMA foreign_keys=OFF;`)
+        if err != nil {
+            return err
+        }
+        defer conn.ExecContext(ctx, `PRAGMA foreign_keys=ON;`)
+    }
+
+    _, err = conn.ExecContext(
+        ctx,
+        `CREATE TABLE IF NOT EXISTS schema_version (
+            version integer PRIMARY KEY,
+            name varchar(250) NOT NULL,
+            checksum char(64) NOT NULL,
+            applied_at timestamp NOT NULL
+        );`,
+    )
+    if err != nil {
+        return err
+    }
+
+    return f(conn, migrations)
+}
+
+func (m *migrator) applied(conn *sql.Conn) (map[int]MigrationStatus, error) {
+    rows, err := conn.QueryContext(
+        context.Background(),
+        `SELECT version, name, checksum, applied_at FROM schema_version;`,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    res := map[int]MigrationStatus{}
+
+    for rows.Next() {
+        s := MigrationStatus{Applied: true}
+
+        err = rows.Scan(&s.Version, &s.Name, &s.Checksum, &s.AppliedAt)
+        if err != nil {
+            return nil, err
+        }
+
+        res[s.Version] = s
+    }
+
+    return res, rows.Err()
+}
+
+// verify makes sure that nothing already applied was edited afterwards
+func (m *migrator) verify(migrations []Migration, applied map[int]MigrationStatus) error {
+    known := map[int]bool{}
+
+    for _, mig := range migrations {
+        known[mig.Version] = true
+
+        s, ok := applied[mig.Version]
+        if ok && strings.TrimSpace(s.Checksum) != mig.Checksum {
+            return fmt.Errorf(
+                "[ERROR] Checksum mismatch for applied migration %d (%s): database has %s, binary has %s",
+                mig.Version,
+                mig.Name,
+                s.Checksum,
+                mig.Checksum,
+            )
+        }
+    }
+
+    for v := range applied {
+        if !known[v] {
+            return fmt.Errorf(
+                "[ERROR] Database is at migration %d which this binary does not know about", v,
+            )
+        }
+    }
+
+    return nil
+}
+
+func (m *migrator) run(conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+    ctx := context.Background()
+
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    _, err = tx.ExecContext(ctx, script)
+    if err != nil {
+        return err
+    }
+
+    err = record(tx)
+    if err != nil {
+        return err
+    }
+
+    if m.dialect == "sqlite" {
+        rows, err := tx.QueryContext(ctx, `PRAGMA foreign_key_check;`)
+        if err != nil {
+            return err
+        }
+
+        violated := rows.Next()
+        rows.Close()
+
+        if violated {
+            return fmt.Errorf("[ERROR] Migration leaves rows violating foreign keys")
+        }
+    }
+
+    return tx.Commit()
+}
+
+func (m *migrator) Up() error {
+    return m.withConn(func(conn *sql.Conn, migrations []Migration) error {
+        applied, err := m.applied(conn)
+        if err != nil {
+            return err
+        }
+
+        err = m.verify(migrations, applied)
+        if err != nil {
+            return err
+        }
+
+        for _, mig := range migrations {
+            if _, ok := applied[mig.Version]; ok {
+                continue
+            }
+
+            err = m.run(conn, mig.Up, func(tx *sql.Tx) error {
+                _, err := tx.Exec(
+                    `INSERT INTO schema_version (version, name, checksum, applied_at)
+                    VALUES ($1, $2, $3, $4);`,
+                    mig.Version,
+                    mig.Name,
+                    mig.Checksum,
+                    time.Now().UTC(),
+                )
+                return err
+            })
+            if err != nil {
+                return fmt.Errorf(
+                    "[ERROR] Failed to apply migration %d (%s): %s", mig.Version, mig.Name, err.Error(),
+                )
+            }
+
+            logs.Infof("Applied migration %d (%s)", mig.Version, mig.Name)
+        }
+
+        return nil
+    })
+}
+
+// Down reverts the last steps applied migrations
+func (m *migrator) Down(steps int) error {
+    return m.withConn(func(conn *sql.Conn, migrations []Migration) error {
+        applied, err := m.applied(conn)
+        if err != nil {
+            return err
+        }
+
+        err = m.verify(migrations, applied)
+        if err != nil {
+            return err
+        }
+
+        for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+            mig := migrations[i]
+
+            if _, ok := applied[mig.Version]; !ok {
+                continue
+            }
+
+            if len(mig.Down) == 0 {
+                return fmt.Errorf(
+                    "[ERROR] Migration %d (%s) cannot be reverted (no down script)", mig.Version, mig.Name,
+                )
+            }
+
+            err = m.run(conn, mig.Down, func(tx *sql.Tx) error {
+                _, err := tx.Exec(`DELETE FROM schema_version WHERE version=$1;`, mig.Version)
+                return err
+            })
+            if err != nil {
+                return fmt.Errorf(
+                    "[ERROR] Failed to revert migration %d (%s): %s", mig.Version, mig.Name, err.Error(),
+                )
+            }
+
+            logs.Infof("Reverted migration %d (%s)", mig.Version, mig.Name)
+            steps -= 1
+        }
+
+        return nil
+    })
+}
+
+func (m *migrator) Status() ([]MigrationStatus, error) {
+    var res []MigrationStatus
+
+    err := m.withConn(func(conn *sql.Conn, migrations []Migration) error {
+        applied, err := m.applied(conn)
+        if err != nil {
+            return err
+        }
+
+        for _, mig := range migrations {
+            s := MigrationStatus{Migration: mig}
+
+            if a, ok := applied[mig.Version]; ok {
+                s.Applied = true
+                s.AppliedAt = a.AppliedAt
+            }
+
+            res = append(res, s)
+        }
+
+        return m.verify(migrations, applied)
+    })
+
+    return res, err
+}
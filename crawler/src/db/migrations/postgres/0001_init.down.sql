@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bets;
+DROP TABLE IF EXISTS games;
@@ -0,0 +1,16 @@
+-- Adopts databases created by hand from the old README schema
+CREATE TABLE IF NOT EXISTS games (
+    game_id serial PRIMARY KEY,
+    tournament character varying(250),
+    radiant character varying(250),
+    dire character varying(250),
+    date timestamp with time zone,
+    CONSTRAINT games_radiant_date_key UNIQUE (radiant, date)
+);
+
+CREATE TABLE IF NOT EXISTS bets (
+    bet_id serial PRIMARY KEY,
+    type character varying(250),
+    bet text[],
+    game_id integer REFERENCES games(game_id)
+);
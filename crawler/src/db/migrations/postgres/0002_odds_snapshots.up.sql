@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS odds_snapshots (
+    snapshot_id serial PRIMARY KEY,
+    game_id integer NOT NULL REFERENCES games(game_id),
+    market character varying(250),
+    outcome character varying(250),
+    value character varying(50),
+    source character varying(100),
+    captured_at timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS odds_snapshots_latest_idx
+    ON odds_snapshots (game_id, market, outcome, captured_at DESC);
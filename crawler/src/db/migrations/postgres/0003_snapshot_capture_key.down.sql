@@ -0,0 +1 @@
+DROP INDEX IF EXISTS odds_snapshots_capture_key;
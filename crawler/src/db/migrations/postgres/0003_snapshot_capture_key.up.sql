@@ -0,0 +1,3 @@
+-- Lets a retried write of the same crawl be ignored with ON CONFLICT DO NOTHING
+CREATE UNIQUE INDEX IF NOT EXISTS odds_snapshots_capture_key
+    ON odds_snapshots (game_id, market, outcome, captured_at);
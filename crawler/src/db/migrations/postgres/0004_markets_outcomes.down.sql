@@ -0,0 +1,27 @@
+DROP INDEX odds_snapshots_latest_idx;
+DROP INDEX odds_snapshots_capture_key;
+
+DELETE FROM odds_snapshots WHERE source='legacy';
+
+ALTER TABLE odds_snapshots RENAME COLUMN raw_value TO value;
+ALTER TABLE odds_snapshots ADD COLUMN game_id integer REFERENCES games(game_id);
+ALTER TABLE odds_snapshots ADD COLUMN market character varying(250);
+ALTER TABLE odds_snapshots ADD COLUMN outcome character varying(250);
+
+UPDATE odds_snapshots s
+SET game_id=m.game_id, market=m.name, outcome=o.label
+FROM outcomes o
+JOIN markets m ON m.market_id=o.market_id
+WHERE o.outcome_id=s.outcome_id;
+
+ALTER TABLE odds_snapshots ALTER COLUMN game_id SET NOT NULL;
+ALTER TABLE odds_snapshots DROP COLUMN outcome_id;
+ALTER TABLE odds_snapshots DROP COLUMN price;
+
+DROP TABLE outcomes;
+DROP TABLE markets;
+
+CREATE UNIQUE INDEX odds_snapshots_capture_key
+    ON odds_snapshots (game_id, market, outcome, captured_at);
+CREATE INDEX odds_snapshots_latest_idx
+    ON odds_snapshots (game_id, market, outcome, captured_at DESC);
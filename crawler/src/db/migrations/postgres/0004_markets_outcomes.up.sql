@@ -0,0 +1,81 @@
+CREATE TABLE markets (
+    market_id serial PRIMARY KEY,
+    game_id integer NOT NULL REFERENCES games(game_id),
+    name character varying(250) NOT NULL,
+    CONSTRAINT markets_game_name_key UNIQUE (game_id, name)
+);
+
+CREATE TABLE outcomes (
+    outcome_id serial PRIMARY KEY,
+    market_id integer NOT NULL REFERENCES markets(market_id),
+    label character varying(250) NOT NULL,
+    ordinal integer NOT NULL,
+    CONSTRAINT outcomes_market_label_key UNIQUE (market_id, label)
+);
+
+-- Markets and outcomes already seen in snapshots
+INSERT INTO markets (game_id, name)
+SELECT DISTINCT game_id, coalesce(market, '')
+FROM odds_snapshots
+ON CONFLICT (game_id, name) DO NOTHING;
+
+INSERT INTO outcomes (market_id, label, ordinal)
+SELECT m.market_id, coalesce(s.outcome, ''),
+    row_number() OVER (PARTITION BY m.market_id ORDER BY min(s.snapshot_id)) - 1
+FROM odds_snapshots s
+JOIN markets m ON m.game_id=s.game_id AND m.name=coalesce(s.market, '')
+GROUP BY m.market_id, coalesce(s.outcome, '')
+ON CONFLICT (market_id, label) DO NOTHING;
+
+-- Legacy bets rows keep options as {{name, value}, ...} arrays
+INSERT INTO markets (game_id, name)
+SELECT DISTINCT game_id, coalesce(type, '')
+FROM bets
+WHERE game_id IS NOT NULL
+ON CONFLICT (game_id, name) DO NOTHING;
+
+INSERT INTO outcomes (market_id, label, ordinal)
+SELECT DISTINCT ON (m.market_id, coalesce(b.bet[i][1], ''))
+    m.market_id, coalesce(b.bet[i][1], ''), i - 1
+FROM bets b
+JOIN markets m ON m.game_id=b.game_id AND m.name=coalesce(b.type, '')
+CROSS JOIN LATERAL generate_subscripts(b.bet, 1) AS i
+ORDER BY m.market_id, coalesce(b.bet[i][1], ''), i
+ON CONFLICT (market_id, label) DO NOTHING;
+
+ALTER TABLE odds_snapshots ADD COLUMN outcome_id integer REFERENCES outcomes(outcome_id);
+ALTER TABLE odds_snapshots ADD COLUMN price numeric(10, 3);
+
+UPDATE odds_snapshots s
+SET outcome_id=o.outcome_id
+FROM markets m
+JOIN outcomes o ON o.market_id=m.market_id
+WHERE m.game_id=s.game_id AND m.name=coalesce(s.market, '') AND o.label=coalesce(s.outcome, '');
+
+-- bets had no capture time, legacy rows are put at the epoch in bet_id order
+INSERT INTO odds_snapshots (game_id, market, outcome, value, source, captured_at, outcome_id)
+SELECT b.game_id, b.type, b.bet[i][1], b.bet[i][2], 'legacy',
+    to_timestamp(0) + b.bet_id * interval '1 second', o.outcome_id
+FROM bets b
+JOIN markets m ON m.game_id=b.game_id AND m.name=coalesce(b.type, '')
+CROSS JOIN LATERAL generate_subscripts(b.bet, 1) AS i
+JOIN outcomes o ON o.market_id=m.market_id AND o.label=coalesce(b.bet[i][1], '')
+ON CONFLICT DO NOTHING;
+
+UPDATE odds_snapshots
+SET price=replace(value, ',', '.')::numeric
+WHERE replace(value, ',', '.') ~ '^[0-9]+(\.[0-9]+)?$';
+
+DROP INDEX odds_snapshots_latest_idx;
+DROP INDEX odds_snapshots_capture_key;
+
+ALTER TABLE odds_snapshots ALTER COLUMN outcome_id SET NOT NULL;
+ALTER TABLE odds_snapshots DROP COLUMN game_id;
+ALTER TABLE odds_snapshots DROP COLUMN market;
+ALTER TABLE odds_snapshots DROP COLUMN outcome;
+ALTER TABLE odds_snapshots RENAME COLUMN value TO raw_value;
+
+CREATE UNIQUE INDEX odds_snapshots_capture_key ON odds_snapshots (outcome_id, captured_at);
+CREATE INDEX odds_snapshots_latest_idx ON odds_snapshots (outcome_id, captured_at DESC);
+
+-- bets is left in place, drop it by hand once the migrated data is checked
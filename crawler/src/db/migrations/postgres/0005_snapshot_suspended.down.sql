@@ -0,0 +1 @@
+ALTER TABLE odds_snapshots DROP COLUMN suspended;
@@ -0,0 +1,5 @@
+ALTER TABLE odds_snapshots ADD COLUMN suspended boolean NOT NULL DEFAULT false;
+
+UPDATE odds_snapshots
+SET suspended=true
+WHERE price IS NULL AND trim(coalesce(raw_value, '')) IN ('', '-', '—', '–');
@@ -0,0 +1,10 @@
+ALTER TABLE games DROP CONSTRAINT games_source_radiant_date_key;
+ALTER TABLE games ADD CONSTRAINT games_radiant_date_key UNIQUE (radiant, date);
+
+ALTER TABLE games DROP COLUMN run_id;
+ALTER TABLE games DROP COLUMN source_url;
+ALTER TABLE games DROP COLUMN source;
+ALTER TABLE odds_snapshots DROP COLUMN run_id;
+
+DROP TABLE crawl_runs;
+DROP TABLE bookmakers;
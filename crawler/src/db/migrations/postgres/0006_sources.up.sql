@@ -0,0 +1,54 @@
+CREATE TABLE bookmakers (
+    code character varying(50) PRIMARY KEY,
+    name character varying(250) NOT NULL,
+    base_url character varying(250)
+);
+
+INSERT INTO bookmakers (code, name, base_url) VALUES
+    ('leon', 'Leon', 'https://leon.ru'),
+    ('ligastavok', 'Liga Stavok', 'https://www.ligastavok.ru'),
+    ('d2lounge', 'Dota 2 Lounge', 'https://dota2lounge.com'),
+    ('ggbet', 'GG.BET', 'https://the-ggbet.com')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE crawl_runs (
+    run_id serial PRIMARY KEY,
+    source character varying(50) NOT NULL REFERENCES bookmakers(code),
+    start_url text,
+    started_at timestamp with time zone NOT NULL,
+    finished_at timestamp with time zone,
+    status character varying(20) NOT NULL,
+    error text
+);
+
+-- Snapshots used to be tagged with the host name
+UPDATE odds_snapshots
+SET source=CASE source
+    WHEN 'leon.ru' THEN 'leon'
+    WHEN 'ligastavok.ru' THEN 'ligastavok'
+    WHEN 'dota2lounge.com' THEN 'd2lounge'
+    WHEN 'the-ggbet.com' THEN 'ggbet'
+    ELSE source
+END;
+
+ALTER TABLE odds_snapshots ADD COLUMN run_id integer REFERENCES crawl_runs(run_id);
+
+ALTER TABLE games ADD COLUMN source character varying(50) REFERENCES bookmakers(code);
+ALTER TABLE games ADD COLUMN source_url text;
+ALTER TABLE games ADD COLUMN run_id integer REFERENCES crawl_runs(run_id);
+
+UPDATE games g
+SET source=latest.source
+FROM (
+    SELECT DISTINCT ON (m.game_id) m.game_id, s.source
+    FROM odds_snapshots s
+    JOIN outcomes o ON o.outcome_id=s.outcome_id
+    JOIN markets m ON m.market_id=o.market_id
+    JOIN bookmakers b ON b.code=s.source
+    ORDER BY m.game_id, s.captured_at DESC
+) latest
+WHERE latest.game_id=g.game_id;
+
+-- The same match may now be stored once per bookmaker
+ALTER TABLE games DROP CONSTRAINT games_radiant_date_key;
+ALTER TABLE games ADD CONSTRAINT games_source_radiant_date_key UNIQUE (source, radiant, date);
@@ -0,0 +1,3 @@
+ALTER TABLE games DROP COLUMN event_id;
+
+DROP TABLE events;
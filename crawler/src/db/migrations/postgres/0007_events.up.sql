@@ -0,0 +1,15 @@
+-- A canonical match that games from different bookmakers are linked to.
+-- Games stored before this migration are linked the next time they are saved
+CREATE TABLE events (
+    event_id serial PRIMARY KEY,
+    team_a character varying(250) NOT NULL,
+    team_b character varying(250) NOT NULL,
+    starts_at timestamp with time zone NOT NULL,
+    tournament character varying(250)
+);
+
+CREATE INDEX events_starts_at_idx ON events (starts_at);
+
+ALTER TABLE games ADD COLUMN event_id integer REFERENCES events(event_id);
+
+CREATE INDEX games_event_id_idx ON games (event_id);
@@ -0,0 +1,2 @@
+DROP TABLE team_aliases;
+DROP TABLE teams;
@@ -0,0 +1,17 @@
+CREATE TABLE teams (
+    team_id serial PRIMARY KEY,
+    name character varying(250) NOT NULL,
+    CONSTRAINT teams_name_key UNIQUE (name)
+);
+
+-- Spellings seen on the sites. team_id is NULL until the alias is approved,
+-- suggested_team_id is the closest known team at the time it was seen
+CREATE TABLE team_aliases (
+    alias character varying(250) PRIMARY KEY,
+    team_id integer REFERENCES teams(team_id),
+    suggested_team_id integer REFERENCES teams(team_id),
+    score real,
+    source character varying(50),
+    first_seen timestamp with time zone NOT NULL,
+    last_seen timestamp with time zone NOT NULL
+);
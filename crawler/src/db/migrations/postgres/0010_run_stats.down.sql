@@ -0,0 +1,3 @@
+ALTER TABLE crawl_runs DROP COLUMN errors;
+ALTER TABLE crawl_runs DROP COLUMN failed;
+ALTER TABLE crawl_runs DROP COLUMN pages;
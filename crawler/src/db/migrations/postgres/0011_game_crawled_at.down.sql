@@ -0,0 +1,3 @@
+DROP INDEX games_source_date_idx;
+
+ALTER TABLE games DROP COLUMN crawled_at;
@@ -0,0 +1,25 @@
+-- Match pages waiting to be crawled. A job is claimed by one worker for the
+-- duration of its lease, a job whose lease ran out (the worker died) is
+-- claimed again until it used up its attempts and goes to the dead letter
+-- state with the last error
+CREATE TABLE crawl_jobs (
+    job_id bigserial PRIMARY KEY,
+    source character varying(50) NOT NULL REFERENCES bookmakers(code),
+    start_url text NOT NULL,
+    url text NOT NULL,
+    run_id integer REFERENCES crawl_runs(run_id),
+    state character varying(20) NOT NULL DEFAULT 'queued',
+    attempts integer NOT NULL DEFAULT 0,
+    worker text,
+    lease_until timestamp with time zone,
+    error_class character varying(50),
+    error text,
+    created_at timestamp with time zone NOT NULL,
+    updated_at timestamp with time zone NOT NULL
+);
+
+-- a page is queued once however many crawlers list it
+CREATE UNIQUE INDEX crawl_jobs_active_key ON crawl_jobs (source, url)
+    WHERE state IN ('queued', 'running');
+
+CREATE INDEX crawl_jobs_claim_idx ON crawl_jobs (state, job_id);
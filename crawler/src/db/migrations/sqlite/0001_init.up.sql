@@ -0,0 +1,8 @@
+CREATE TABLE games (
+    game_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tournament TEXT,
+    radiant TEXT,
+    dire TEXT,
+    date TIMESTAMP,
+    UNIQUE (radiant, date)
+);
@@ -0,0 +1 @@
+DROP TABLE odds_snapshots;
@@ -0,0 +1,12 @@
+CREATE TABLE odds_snapshots (
+    snapshot_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL REFERENCES games(game_id),
+    market TEXT,
+    outcome TEXT,
+    value TEXT,
+    source TEXT,
+    captured_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX odds_snapshots_latest_idx
+    ON odds_snapshots (game_id, market, outcome, captured_at DESC);
@@ -0,0 +1 @@
+DROP INDEX odds_snapshots_capture_key;
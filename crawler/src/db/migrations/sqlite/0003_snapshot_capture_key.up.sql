@@ -0,0 +1,2 @@
+CREATE UNIQUE INDEX odds_snapshots_capture_key
+    ON odds_snapshots (game_id, market, outcome, captured_at);
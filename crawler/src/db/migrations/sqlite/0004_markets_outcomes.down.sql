@@ -0,0 +1,26 @@
+CREATE TABLE odds_snapshots_old (
+    snapshot_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL REFERENCES games(game_id),
+    market TEXT,
+    outcome TEXT,
+    value TEXT,
+    source TEXT,
+    captured_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO odds_snapshots_old (snapshot_id, game_id, market, outcome, value, source, captured_at)
+SELECT s.snapshot_id, m.game_id, m.name, o.label, s.raw_value, s.source, s.captured_at
+FROM odds_snapshots s
+JOIN outcomes o ON o.outcome_id=s.outcome_id
+JOIN markets m ON m.market_id=o.market_id;
+
+DROP TABLE odds_snapshots;
+ALTER TABLE odds_snapshots_old RENAME TO odds_snapshots;
+
+DROP TABLE outcomes;
+DROP TABLE markets;
+
+CREATE UNIQUE INDEX odds_snapshots_capture_key
+    ON odds_snapshots (game_id, market, outcome, captured_at);
+CREATE INDEX odds_snapshots_latest_idx
+    ON odds_snapshots (game_id, market, outcome, captured_at DESC);
@@ -0,0 +1,47 @@
+CREATE TABLE markets (
+    market_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL REFERENCES games(game_id),
+    name TEXT NOT NULL,
+    UNIQUE (game_id, name)
+);
+
+CREATE TABLE outcomes (
+    outcome_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    market_id INTEGER NOT NULL REFERENCES markets(market_id),
+    label TEXT NOT NULL,
+    ordinal INTEGER NOT NULL,
+    UNIQUE (market_id, label)
+);
+
+INSERT OR IGNORE INTO markets (game_id, name)
+SELECT DISTINCT game_id, coalesce(market, '') FROM odds_snapshots;
+
+INSERT OR IGNORE INTO outcomes (market_id, label, ordinal)
+SELECT m.market_id, coalesce(s.outcome, ''),
+    row_number() OVER (PARTITION BY m.market_id ORDER BY min(s.snapshot_id)) - 1
+FROM odds_snapshots s
+JOIN markets m ON m.game_id=s.game_id AND m.name=coalesce(s.market, '')
+GROUP BY m.market_id, coalesce(s.outcome, '');
+
+CREATE TABLE odds_snapshots_new (
+    snapshot_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    outcome_id INTEGER NOT NULL REFERENCES outcomes(outcome_id),
+    price REAL,
+    raw_value TEXT,
+    source TEXT,
+    captured_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO odds_snapshots_new (snapshot_id, outcome_id, price, raw_value, source, captured_at)
+SELECT s.snapshot_id, o.outcome_id,
+    CASE WHEN replace(s.value, ',', '.') GLOB '[0-9]*' THEN CAST(replace(s.value, ',', '.') AS REAL) END,
+    s.value, s.source, s.captured_at
+FROM odds_snapshots s
+JOIN markets m ON m.game_id=s.game_id AND m.name=coalesce(s.market, '')
+JOIN outcomes o ON o.market_id=m.market_id AND o.label=coalesce(s.outcome, '');
+
+DROP TABLE odds_snapshots;
+ALTER TABLE odds_snapshots_new RENAME TO odds_snapshots;
+
+CREATE UNIQUE INDEX odds_snapshots_capture_key ON odds_snapshots (outcome_id, captured_at);
+CREATE INDEX odds_snapshots_latest_idx ON odds_snapshots (outcome_id, captured_at DESC);
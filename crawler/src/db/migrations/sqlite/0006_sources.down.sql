@@ -0,0 +1,19 @@
+CREATE TABLE games_old (
+    game_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tournament TEXT,
+    radiant TEXT,
+    dire TEXT,
+    date TIMESTAMP,
+    UNIQUE (radiant, date)
+);
+
+INSERT INTO games_old (game_id, tournament, radiant, dire, date)
+SELECT game_id, tournament, radiant, dire, date FROM games;
+
+DROP TABLE games;
+ALTER TABLE games_old RENAME TO games;
+
+ALTER TABLE odds_snapshots DROP COLUMN run_id;
+
+DROP TABLE crawl_runs;
+DROP TABLE bookmakers;
@@ -0,0 +1,60 @@
+CREATE TABLE bookmakers (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    base_url TEXT
+);
+
+INSERT OR IGNORE INTO bookmakers (code, name, base_url) VALUES
+    ('leon', 'Leon', 'https://leon.ru'),
+    ('ligastavok', 'Liga Stavok', 'https://www.ligastavok.ru'),
+    ('d2lounge', 'Dota 2 Lounge', 'https://dota2lounge.com'),
+    ('ggbet', 'GG.BET', 'https://the-ggbet.com');
+
+CREATE TABLE crawl_runs (
+    run_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL REFERENCES bookmakers(code),
+    start_url TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    status TEXT NOT NULL,
+    error TEXT
+);
+
+UPDATE odds_snapshots
+SET source=CASE source
+    WHEN 'leon.ru' THEN 'leon'
+    WHEN 'ligastavok.ru' THEN 'ligastavok'
+    WHEN 'dota2lounge.com' THEN 'd2lounge'
+    WHEN 'the-ggbet.com' THEN 'ggbet'
+    ELSE source
+END;
+
+ALTER TABLE odds_snapshots ADD COLUMN run_id INTEGER;
+
+-- games is rebuilt to replace the (radiant, date) unique key
+CREATE TABLE games_new (
+    game_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tournament TEXT,
+    radiant TEXT,
+    dire TEXT,
+    date TIMESTAMP,
+    source TEXT REFERENCES bookmakers(code),
+    source_url TEXT,
+    run_id INTEGER REFERENCES crawl_runs(run_id),
+    UNIQUE (source, radiant, date)
+);
+
+INSERT INTO games_new (game_id, tournament, radiant, dire, date, source)
+SELECT g.game_id, g.tournament, g.radiant, g.dire, g.date, (
+    SELECT s.source
+    FROM odds_snapshots s
+    JOIN outcomes o ON o.outcome_id=s.outcome_id
+    JOIN markets m ON m.market_id=o.market_id
+    JOIN bookmakers b ON b.code=s.source
+    WHERE m.game_id=g.game_id
+    ORDER BY s.captured_at DESC LIMIT 1
+)
+FROM games g;
+
+DROP TABLE games;
+ALTER TABLE games_new RENAME TO games;
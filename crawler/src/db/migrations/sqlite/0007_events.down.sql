@@ -0,0 +1,5 @@
+DROP INDEX games_event_id_idx;
+
+ALTER TABLE games DROP COLUMN event_id;
+
+DROP TABLE events;
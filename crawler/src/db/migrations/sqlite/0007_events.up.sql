@@ -0,0 +1,14 @@
+CREATE TABLE events (
+    event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_a TEXT NOT NULL,
+    team_b TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    tournament TEXT
+);
+
+CREATE INDEX events_starts_at_idx ON events (starts_at);
+
+-- no REFERENCES, sqlite can not drop a column that is part of a foreign key
+ALTER TABLE games ADD COLUMN event_id INTEGER;
+
+CREATE INDEX games_event_id_idx ON games (event_id);
@@ -0,0 +1,14 @@
+CREATE TABLE teams (
+    team_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE team_aliases (
+    alias TEXT PRIMARY KEY,
+    team_id INTEGER REFERENCES teams(team_id),
+    suggested_team_id INTEGER REFERENCES teams(team_id),
+    score REAL,
+    source TEXT,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL
+);
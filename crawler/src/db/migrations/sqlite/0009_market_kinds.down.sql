@@ -0,0 +1,5 @@
+DROP INDEX markets_kind_idx;
+
+ALTER TABLE markets DROP COLUMN line;
+ALTER TABLE markets DROP COLUMN map_no;
+ALTER TABLE markets DROP COLUMN kind;
@@ -0,0 +1,7 @@
+-- Canonical kind of a market with the map and line parsed from its title.
+-- Existing markets stay 'other' until their game is crawled again
+ALTER TABLE markets ADD COLUMN kind VARCHAR(50) NOT NULL DEFAULT 'other';
+ALTER TABLE markets ADD COLUMN map_no INTEGER;
+ALTER TABLE markets ADD COLUMN line NUMERIC(6, 2);
+
+CREATE INDEX markets_kind_idx ON markets (kind);
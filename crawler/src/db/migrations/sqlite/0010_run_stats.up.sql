@@ -0,0 +1,5 @@
+-- Page counts of a crawl run, errors holds failed attempts by class
+-- ({"timeout": 3, "parse": 1})
+ALTER TABLE crawl_runs ADD COLUMN pages INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE crawl_runs ADD COLUMN failed INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE crawl_runs ADD COLUMN errors TEXT NOT NULL DEFAULT '{}';
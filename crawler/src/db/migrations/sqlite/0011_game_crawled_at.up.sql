@@ -0,0 +1,14 @@
+-- When the page of a game was crawled last, used to decide when it is due
+-- again. Existing games take the time of their latest snapshot
+ALTER TABLE games ADD COLUMN crawled_at TIMESTAMP;
+
+UPDATE games
+SET crawled_at = (
+    SELECT max(s.captured_at)
+    FROM odds_snapshots s
+    JOIN outcomes o ON o.outcome_id=s.outcome_id
+    JOIN markets m ON m.market_id=o.market_id
+    WHERE m.game_id=games.game_id
+);
+
+CREATE INDEX games_source_date_idx ON games (source, date);
@@ -0,0 +1,25 @@
+-- Match pages waiting to be crawled. A job is claimed by one worker for the
+-- duration of its lease, a job whose lease ran out (the worker died) is
+-- claimed again until it used up its attempts and goes to the dead letter
+-- state with the last error
+CREATE TABLE crawl_jobs (
+    job_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL REFERENCES bookmakers(code),
+    start_url TEXT NOT NULL,
+    url TEXT NOT NULL,
+    run_id INTEGER REFERENCES crawl_runs(run_id),
+    state TEXT NOT NULL DEFAULT 'queued',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    worker TEXT,
+    lease_until TIMESTAMP,
+    error_class TEXT,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- a page is queued once however many crawlers list it
+CREATE UNIQUE INDEX crawl_jobs_active_key ON crawl_jobs (source, url)
+    WHERE state IN ('queued', 'running');
+
+CREATE INDEX crawl_jobs_claim_idx ON crawl_jobs (state, job_id);
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	"mxshs/crawler/src/domain"
+)
+
+// StoredPage is what was stored from a match page as of a run: the game
+// (without bets) and the latest snapshot of each of its outcomes. Snapshots
+// are only stored when a value changes, so outcomes that disappeared from
+// the page later are still there
+type StoredPage struct {
+    Game domain.GameBets
+    Snapshots []Snapshot
+}
+
+func storedPage(ctx context.Context, conn *sql.DB, source string, url string, run_id int64) (*StoredPage, error) {
+    page := &StoredPage{}
+
+    var game_id int
+    var date sql.NullTime
+
+    err := conn.QueryRowContext(
+        ctx,
+        `SELECT game_id, date, coalesce(tournament, ''), coalesce(radiant, ''), coalesce(dire, ''), source, source_url
+        FROM games
+        WHERE source=$1 AND source_url=$2
+        ORDER BY game_id DESC LIMIT 1;`,
+        source,
+        url,
+    ).Scan(&game_id, &date, &page.Game.Tournament, &page.Game.TeamA, &page.Game.TeamB, &page.Game.Source, &page.Game.SourceURL)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    page.Game.Date = date.Time
+
+    rows, err := conn.QueryContext(
+        ctx,
+        `SELECT m.name, m.kind, o.label, o.ordinal, s.price, s.suspended,
+            coalesce(s.raw_value, ''), coalesce(s.source, ''), coalesce(s.run_id, 0), s.captured_at
+        FROM outcomes o
+        JOIN markets m ON m.market_id=o.market_id
+        JOIN odds_snapshots s ON s.outcome_id=o.outcome_id
+        WHERE m.game_id=$1 AND s.snapshot_id=(
+            SELECT l.snapshot_id FROM odds_snapshots l
+            WHERE l.outcome_id=o.outcome_id AND ($2=0 OR coalesce(l.run_id, 0) <= $2)
+            ORDER BY l.captured_at DESC, l.snapshot_id DESC LIMIT 1
+        )
+        ORDER BY m.market_id, o.ordinal;`,
+        game_id,
+        run_id,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        s := Snapshot{GameID: game_id}
+
+        err = rows.Scan(
+            &s.Market,
+            &s.Kind,
+            &s.Outcome,
+            &s.Ordinal,
+            &s.Price,
+            &s.Suspended,
+            &s.Value,
+            &s.Source,
+            &s.RunID,
+            &s.CapturedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+
+        page.Snapshots = append(page.Snapshots, s)
+    }
+
+    return page, rows.Err()
+}
+
+func (db *DB) StoredPage(ctx context.Context, source string, url string, run_id int64) (*StoredPage, error) {
+    return storedPage(ctx, db.db, source, url, run_id)
+}
+
+func (db *SQLiteDB) StoredPage(ctx context.Context, source string, url string, run_id int64) (*StoredPage, error) {
+    return storedPage(ctx, db.db, source, url, run_id)
+}
+
+func (db *MemoryDB) StoredPage(ctx context.Context, source string, url string, run_id int64) (*StoredPage, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    game_id := 0
+    for i, g := range db.games {
+        if g.Source == source && g.SourceURL == url {
+            game_id = i + 1
+        }
+    }
+
+    if game_id == 0 {
+        return nil, nil
+    }
+
+    page := &StoredPage{Game: db.games[game_id - 1]}
+
+    type key struct {
+        market string
+        outcome string
+    }
+
+    latest := map[key]int{}
+
+    for i, s := range db.snapshots {
+        if s.GameID != game_id || (run_id != 0 && s.RunID > run_id) {
+            continue
+        }
+
+        k := key{s.Market, s.Outcome}
+        if j, ok := latest[k]; !ok || !s.CapturedAt.Before(db.snapshots[j].CapturedAt) {
+            latest[k] = i
+        }
+    }
+
+    for _, i := range latest {
+        page.Snapshots = append(page.Snapshots, db.snapshots[i])
+    }
+
+    sort.Slice(page.Snapshots, func(i, j int) bool {
+        a, b := page.Snapshots[i], page.Snapshots[j]
+        if a.Market != b.Market {
+            return a.Market < b.Market
+        }
+
+        return a.Ordinal < b.Ordinal
+    })
+
+    return page, nil
+}
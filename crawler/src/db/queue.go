@@ -0,0 +1,567 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+    JobQueued = "queued"
+    JobRunning = "running"
+    JobDone = "done"
+    // the dead letter state: the job failed for good and is kept with its
+    // last error until it is requeued by hand
+    JobDead = "dead"
+)
+
+// Job is a match page to crawl
+type Job struct {
+    ID int64 `json:"id"`
+    Source string `json:"source"`
+    StartURL string `json:"start_url"`
+    URL string `json:"url"`
+    // RunID is the run that queued the page, games are stored under it
+    RunID int64 `json:"run_id"`
+    State string `json:"state"`
+    // Attempts counts the times the job was claimed
+    Attempts int `json:"attempts"`
+    Worker string `json:"worker,omitempty"`
+    LeaseUntil time.Time `json:"lease_until"`
+    ErrorClass string `json:"error_class,omitempty"`
+    Error string `json:"error,omitempty"`
+}
+
+// QueueCount is the number of jobs of a source in a state
+type QueueCount struct {
+    Source string `json:"source"`
+    State string `json:"state"`
+    Jobs int `json:"jobs"`
+}
+
+// Queue is implemented by storages that keep the match pages of a crawl as
+// jobs, so a crawl survives a crash and several crawlers can share the work.
+// Complete, Bury and Release only apply while the worker still holds the
+// lease of the job, a job claimed again by someone else is left alone
+type Queue interface {
+    // Enqueue adds the pages of a listing, pages that are queued or being
+    // crawled already are skipped. Returns the number of new jobs
+    Enqueue(ctx context.Context, run_id int64, source string, start_url string, urls []string) (int, error)
+    // Claim leases the oldest waiting job of source (of any source when
+    // empty) to worker, nil when there is none. Jobs of source whose lease
+    // ran out are claimed again, or buried once they were claimed
+    // maxAttempts times
+    Claim(ctx context.Context, source string, worker string, lease time.Duration, maxAttempts int) (*Job, error)
+    Complete(ctx context.Context, job *Job) error
+    // Retry puts a failed job back to be claimed again, the attempt is used
+    // up and the error kept until the job is finished
+    Retry(ctx context.Context, job *Job, class string, jobErr error) error
+    // Bury moves the job to the dead letter state with its error
+    Bury(ctx context.Context, job *Job, class string, jobErr error) error
+    // Release puts an unfinished job back without using up an attempt, e.g.
+    // when the crawler is shutting down
+    Release(ctx context.Context, job *Job) error
+    QueueCounts(ctx context.Context) ([]QueueCount, error)
+    // Requeue moves the dead jobs of source (all when empty) back to the
+    // queue with their attempts reset
+    Requeue(ctx context.Context, source string) (int, error)
+    // Purge deletes jobs that were done before the given time
+    Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// The queue is shared by the SQL storages, only claiming differs: Postgres
+// locks the claimed row and skips rows locked by other crawlers, sqlite has
+// a single writer anyway
+
+func enqueue(ctx context.Context, conn *sql.DB, run_id int64, source string, start_url string, urls []string) (int, error) {
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    added := 0
+    now := time.Now().UTC()
+
+    for _, url := range urls {
+        res, err := tx.ExecContext(
+            ctx,
+            `INSERT INTO crawl_jobs (source, start_url, url, run_id, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $5)
+            ON CONFLICT (source, url) WHERE state IN ('queued', 'running') DO NOTHING;`,
+            source,
+            start_url,
+            url,
+            runID(run_id),
+            now,
+        )
+        if err != nil {
+            return added, err
+        }
+
+        n, err := res.RowsAffected()
+        if err != nil {
+            return added, err
+        }
+
+        added += int(n)
+    }
+
+    return added, tx.Commit()
+}
+
+func claim(
+    ctx context.Context,
+    conn *sql.DB,
+    lock string,
+    source string,
+    worker string,
+    lease time.Duration,
+    maxAttempts int,
+) (*Job, error) {
+    now := time.Now().UTC()
+
+    _, err := conn.ExecContext(
+        ctx,
+        `UPDATE crawl_jobs SET state=$1, error_class=$2, error=$3, updated_at=$4
+        WHERE state=$5 AND lease_until < $4 AND attempts >= $6 AND ($7 = '' OR source=$7);`,
+        JobDead,
+        "lease_expired",
+        fmt.Sprintf("lease ran out %d times, the crawler died on the page", maxAttempts),
+        now,
+        JobRunning,
+        maxAttempts,
+        source,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    job := &Job{}
+    var lease_until sql.NullTime
+
+    err = conn.QueryRowContext(
+        ctx,
+        `UPDATE crawl_jobs SET state=$1, attempts=attempts+1, worker=$2, lease_until=$3, updated_at=$4
+        WHERE job_id = (
+            SELECT job_id FROM crawl_jobs
+            WHERE ($5 = '' OR source=$5) AND (state=$6 OR (state=$1 AND lease_until < $4))
+            ORDER BY job_id
+            LIMIT 1 ` + lock + `
+        )
+        RETURNING job_id, source, start_url, url, coalesce(run_id, 0), state, attempts, worker, lease_until;`,
+        JobRunning,
+        worker,
+        now.Add(lease),
+        now,
+        source,
+        JobQueued,
+    ).Scan(
+        &job.ID,
+        &job.Source,
+        &job.StartURL,
+        &job.URL,
+        &job.RunID,
+        &job.State,
+        &job.Attempts,
+        &job.Worker,
+        &lease_until,
+    )
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    job.LeaseUntil = lease_until.Time
+
+    return job, nil
+}
+
+// finishJob moves a job this worker holds to state
+func finishJob(ctx context.Context, conn *sql.DB, job *Job, state string, class string, jobErr error, attempts int) error {
+    msg := sql.NullString{}
+    if jobErr != nil {
+        msg = sql.NullString{String: jobErr.Error(), Valid: true}
+    }
+
+    errClass := sql.NullString{String: class, Valid: len(class) > 0}
+
+    _, err := conn.ExecContext(
+        ctx,
+        `UPDATE crawl_jobs SET state=$1, error_class=$2, error=$3, attempts=attempts+$4, lease_until=NULL, updated_at=$5
+        WHERE job_id=$6 AND worker=$7 AND state=$8;`,
+        state,
+        errClass,
+        msg,
+        attempts,
+        time.Now().UTC(),
+        job.ID,
+        job.Worker,
+        JobRunning,
+    )
+
+    return err
+}
+
+func queueCounts(ctx context.Context, conn *sql.DB) ([]QueueCount, error) {
+    rows, err := conn.QueryContext(
+        ctx,
+        `SELECT source, state, count(*) FROM crawl_jobs GROUP BY source, state ORDER BY source, state;`,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var res []QueueCount
+
+    for rows.Next() {
+        var c QueueCount
+
+        err = rows.Scan(&c.Source, &c.State, &c.Jobs)
+        if err != nil {
+            return nil, err
+        }
+
+        res = append(res, c)
+    }
+
+    return res, rows.Err()
+}
+
+func requeue(ctx context.Context, conn *sql.DB, source string) (int, error) {
+    // a page that was queued again since it died stays dead, as do all but
+    // the last job of a page that died more than once
+    res, err := conn.ExecContext(
+        ctx,
+        `UPDATE crawl_jobs SET state=$1, attempts=0, worker=NULL, lease_until=NULL, updated_at=$2
+        WHERE state=$3 AND ($4 = '' OR source=$4)
+            AND job_id = (
+                SELECT max(d.job_id) FROM crawl_jobs d
+                WHERE d.source=crawl_jobs.source AND d.url=crawl_jobs.url AND d.state=$3
+            )
+            AND NOT EXISTS (
+                SELECT 1 FROM crawl_jobs a
+                WHERE a.source=crawl_jobs.source AND a.url=crawl_jobs.url AND a.state IN ($1, $5)
+            );`,
+        JobQueued,
+        time.Now().UTC(),
+        JobDead,
+        source,
+        JobRunning,
+    )
+    if err != nil {
+        return 0, err
+    }
+
+    n, err := res.RowsAffected()
+
+    return int(n), err
+}
+
+func purge(ctx context.Context, conn *sql.DB, before time.Time) (int, error) {
+    res, err := conn.ExecContext(
+        ctx,
+        `DELETE FROM crawl_jobs WHERE state=$1 AND updated_at < $2;`,
+        JobDone,
+        before.UTC(),
+    )
+    if err != nil {
+        return 0, err
+    }
+
+    n, err := res.RowsAffected()
+
+    return int(n), err
+}
+
+func (db *DB) Enqueue(ctx context.Context, run_id int64, source string, start_url string, urls []string) (int, error) {
+    return enqueue(ctx, db.db, run_id, source, start_url, urls)
+}
+
+func (db *DB) Claim(ctx context.Context, source string, worker string, lease time.Duration, maxAttempts int) (*Job, error) {
+    return claim(ctx, db.db, "FOR UPDATE SKIP LOCKED", source, worker, lease, maxAttempts)
+}
+
+func (db *DB) Complete(ctx context.Context, job *Job) error {
+    return finishJob(ctx, db.db, job, JobDone, "", nil, 0)
+}
+
+func (db *DB) Retry(ctx context.Context, job *Job, class string, jobErr error) error {
+    return finishJob(ctx, db.db, job, JobQueued, class, jobErr, 0)
+}
+
+func (db *DB) Bury(ctx context.Context, job *Job, class string, jobErr error) error {
+    return finishJob(ctx, db.db, job, JobDead, class, jobErr, 0)
+}
+
+func (db *DB) Release(ctx context.Context, job *Job) error {
+    return finishJob(ctx, db.db, job, JobQueued, "", nil, -1)
+}
+
+func (db *DB) QueueCounts(ctx context.Context) ([]QueueCount, error) {
+    return queueCounts(ctx, db.db)
+}
+
+func (db *DB) Requeue(ctx context.Context, source string) (int, error) {
+    return requeue(ctx, db.db, source)
+}
+
+func (db *DB) Purge(ctx context.Context, before time.Time) (int, error) {
+    return purge(ctx, db.db, before)
+}
+
+func (db *SQLiteDB) Enqueue(ctx context.Context, run_id int64, source string, start_url string, urls []string) (int, error) {
+    return enqueue(ctx, db.db, run_id, source, start_url, urls)
+}
+
+func (db *SQLiteDB) Claim(ctx context.Context, source string, worker string, lease time.Duration, maxAttempts int) (*Job, error) {
+    return claim(ctx, db.db, "", source, worker, lease, maxAttempts)
+}
+
+func (db *SQLiteDB) Complete(ctx context.Context, job *Job) error {
+    return finishJob(ctx, db.db, job, JobDone, "", nil, 0)
+}
+
+func (db *SQLiteDB) Retry(ctx context.Context, job *Job, class string, jobErr error) error {
+    return finishJob(ctx, db.db, job, JobQueued, class, jobErr, 0)
+}
+
+func (db *SQLiteDB) Bury(ctx context.Context, job *Job, class string, jobErr error) error {
+    return finishJob(ctx, db.db, job, JobDead, class, jobErr, 0)
+}
+
+func (db *SQLiteDB) Release(ctx context.Context, job *Job) error {
+    return finishJob(ctx, db.db, job, JobQueued, "", nil, -1)
+}
+
+func (db *SQLiteDB) QueueCounts(ctx context.Context) ([]QueueCount, error) {
+    return queueCounts(ctx, db.db)
+}
+
+func (db *SQLiteDB) Requeue(ctx context.Context, source string) (int, error) {
+    return requeue(ctx, db.db, source)
+}
+
+func (db *SQLiteDB) Purge(ctx context.Context, before time.Time) (int, error) {
+    return purge(ctx, db.db, before)
+}
+
+func (db *MemoryDB) Enqueue(ctx context.Context, run_id int64, source string, start_url string, urls []string) (int, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    added := 0
+
+    for _, url := range urls {
+        if db.activeJob(source, url) {
+            continue
+        }
+
+        db.lastJob++
+
+        db.jobs = append(db.jobs, Job{
+            ID: db.lastJob,
+            Source: source,
+            StartURL: start_url,
+            URL: url,
+            RunID: run_id,
+            State: JobQueued,
+        })
+        db.jobUpdated = append(db.jobUpdated, time.Now())
+        added++
+    }
+
+    return added, nil
+}
+
+func (db *MemoryDB) activeJob(source string, url string) bool {
+    for _, j := range db.jobs {
+        if j.Source == source && j.URL == url && (j.State == JobQueued || j.State == JobRunning) {
+            return true
+        }
+    }
+
+    return false
+}
+
+func (db *MemoryDB) Claim(ctx context.Context, source string, worker string, lease time.Duration, maxAttempts int) (*Job, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    now := time.Now()
+
+    for i := range db.jobs {
+        j := &db.jobs[i]
+
+        if len(source) > 0 && j.Source != source {
+            continue
+        }
+
+        expired := j.State == JobRunning && j.LeaseUntil.Before(now)
+        if expired && j.Attempts >= maxAttempts {
+            j.State = JobDead
+            j.ErrorClass = "lease_expired"
+            j.Error = fmt.Sprintf("lease ran out %d times, the crawler died on the page", maxAttempts)
+            db.jobUpdated[i] = now
+            continue
+        }
+
+        if j.State != JobQueued && !expired {
+            continue
+        }
+
+        j.State = JobRunning
+        j.Attempts++
+        j.Worker = worker
+        j.LeaseUntil = now.Add(lease)
+        db.jobUpdated[i] = now
+
+        claimed := *j
+
+        return &claimed, nil
+    }
+
+    return nil, nil
+}
+
+func (db *MemoryDB) finishJob(job *Job, state string, class string, jobErr error, attempts int) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    i := db.findJob(job.ID)
+    if i < 0 {
+        return
+    }
+
+    j := &db.jobs[i]
+    if j.Worker != job.Worker || j.State != JobRunning {
+        return
+    }
+
+    j.State = state
+    j.ErrorClass = class
+    j.Error = ""
+    if jobErr != nil {
+        j.Error = jobErr.Error()
+    }
+    j.Attempts += attempts
+    j.LeaseUntil = time.Time{}
+    db.jobUpdated[i] = time.Now()
+}
+
+// findJob is the index of the job with id in jobs, -1 once it was purged
+func (db *MemoryDB) findJob(id int64) int {
+    i := sort.Search(len(db.jobs), func(i int) bool {
+        return db.jobs[i].ID >= id
+    })
+
+    if i < len(db.jobs) && db.jobs[i].ID == id {
+        return i
+    }
+
+    return -1
+}
+
+func (db *MemoryDB) Complete(ctx context.Context, job *Job) error {
+    db.finishJob(job, JobDone, "", nil, 0)
+    return nil
+}
+
+func (db *MemoryDB) Retry(ctx context.Context, job *Job, class string, jobErr error) error {
+    db.finishJob(job, JobQueued, class, jobErr, 0)
+    return nil
+}
+
+func (db *MemoryDB) Bury(ctx context.Context, job *Job, class string, jobErr error) error {
+    db.finishJob(job, JobDead, class, jobErr, 0)
+    return nil
+}
+
+func (db *MemoryDB) Release(ctx context.Context, job *Job) error {
+    db.finishJob(job, JobQueued, "", nil, -1)
+    return nil
+}
+
+func (db *MemoryDB) QueueCounts(ctx context.Context) ([]QueueCount, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    var res []QueueCount
+
+    for _, j := range db.jobs {
+        found := false
+
+        for i := range res {
+            if res[i].Source == j.Source && res[i].State == j.State {
+                res[i].Jobs++
+                found = true
+            }
+        }
+
+        if !found {
+            res = append(res, QueueCount{Source: j.Source, State: j.State, Jobs: 1})
+        }
+    }
+
+    sort.Slice(res, func(i, k int) bool {
+        if res[i].Source != res[k].Source {
+            return res[i].Source < res[k].Source
+        }
+
+        return res[i].State < res[k].State
+    })
+
+    return res, nil
+}
+
+func (db *MemoryDB) Requeue(ctx context.Context, source string) (int, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    n := 0
+
+    // the last job of a page first, so older ones find it active
+    for i := len(db.jobs) - 1; i >= 0; i-- {
+        j := &db.jobs[i]
+
+        if j.State != JobDead || (len(source) > 0 && j.Source != source) || db.activeJob(j.Source, j.URL) {
+            continue
+        }
+
+        j.State = JobQueued
+        j.Attempts = 0
+        j.Worker = ""
+        db.jobUpdated[i] = time.Now()
+        n++
+    }
+
+    return n, nil
+}
+
+func (db *MemoryDB) Purge(ctx context.Context, before time.Time) (int, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    jobs := db.jobs[:0]
+    updated := db.jobUpdated[:0]
+
+    for i, j := range db.jobs {
+        if j.State == JobDone && db.jobUpdated[i].Before(before) {
+            continue
+        }
+
+        jobs = append(jobs, j)
+        updated = append(updated, db.jobUpdated[i])
+    }
+
+    n := len(db.jobs) - len(jobs)
+    db.jobs = jobs
+    db.jobUpdated = updated
+
+    return n, nil
+}
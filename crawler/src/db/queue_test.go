@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func queueOf(t *testing.T, s Storage) Queue {
+    t.Helper()
+
+    q, ok := s.(Queue)
+    if !ok {
+        t.Fatal("storage has no job queue")
+    }
+
+    return q
+}
+
+// states counts the jobs of every source and state as "source/state"
+func states(t *testing.T, q Queue) map[string]int {
+    t.Helper()
+
+    counts, err := q.QueueCounts(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := map[string]int{}
+    for _, c := range counts {
+        res[c.Source + "/" + c.State] = c.Jobs
+    }
+
+    return res
+}
+
+func TestQueueClaim(t *testing.T) {
+    ctx := context.Background()
+
+    for name, s := range storages(t) {
+        t.Run(name, func(t *testing.T) {
+            q := queueOf(t, s)
+
+            n, err := q.Enqueue(ctx, 0, "leon", "https://leon.ru/", []string{"/1", "/2", "/1"})
+            if err != nil || n != 2 {
+                t.Fatalf("Enqueue = %d, %v, want 2 new jobs", n, err)
+            }
+
+            n, err = q.Enqueue(ctx, 0, "leon", "https://leon.ru/", []string{"/1"})
+            if err != nil || n != 0 {
+                t.Fatalf("Enqueue = %d, %v, want the queued page skipped", n, err)
+            }
+
+            job, err := q.Claim(ctx, "leon", "w1", time.Minute, 3)
+            if err != nil || job == nil || job.URL != "/1" || job.Attempts != 1 || job.Worker != "w1" {
+                t.Fatalf("Claim = %+v, %v, want /1 on its first attempt", job, err)
+            }
+
+            other, err := q.Claim(ctx, "ligastavok", "w2", time.Minute, 3)
+            if err != nil || other != nil {
+                t.Fatalf("Claim of another source = %+v, %v, want none", other, err)
+            }
+
+            err = q.Complete(ctx, job)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            // a worker that lost the lease can not finish the job
+            job, err = q.Claim(ctx, "", "w1", time.Minute, 3)
+            if err != nil || job == nil || job.URL != "/2" {
+                t.Fatalf("Claim = %+v, %v, want /2", job, err)
+            }
+
+            stale := *job
+            stale.Worker = "w2"
+
+            err = q.Bury(ctx, &stale, "parse", errors.New("boom"))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if got := states(t, q); got["leon/done"] != 1 || got["leon/running"] != 1 {
+                t.Errorf("queue %v, want a job done and one running", got)
+            }
+        })
+    }
+}
+
+func TestQueueRetry(t *testing.T) {
+    ctx := context.Background()
+
+    for name, s := range storages(t) {
+        t.Run(name, func(t *testing.T) {
+            q := queueOf(t, s)
+
+            _, err := q.Enqueue(ctx, 0, "leon", "https://leon.ru/", []string{"/1"})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            job, err := q.Claim(ctx, "leon", "w1", time.Minute, 3)
+            if err != nil || job == nil {
+                t.Fatalf("Claim = %+v, %v", job, err)
+            }
+
+            err = q.Retry(ctx, job, "network", errors.New("connection reset"))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            job, err = q.Claim(ctx, "leon", "w1", time.Minute, 3)
+            if err != nil || job == nil || job.Attempts != 2 {
+                t.Fatalf("Claim = %+v, %v, want the retried job on its second attempt", job, err)
+            }
+
+            // releasing does not use up the attempt
+            err = q.Release(ctx, job)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            job, err = q.Claim(ctx, "leon", "w1", time.Minute, 3)
+            if err != nil || job == nil || job.Attempts != 2 {
+                t.Fatalf("Claim = %+v, %v, want the released job on its second attempt", job, err)
+            }
+        })
+    }
+}
+
+func TestQueueLeaseExpired(t *testing.T) {
+    ctx := context.Background()
+
+    for name, s := range storages(t) {
+        t.Run(name, func(t *testing.T) {
+            q := queueOf(t, s)
+
+            _, err := q.Enqueue(ctx, 0, "leon", "https://leon.ru/", []string{"/1"})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            _, err = q.Enqueue(ctx, 0, "ligastavok", "https://ligastavok.ru/", []string{"/1"})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            for _, source := range []string{"leon", "ligastavok"} {
+                job, err := q.Claim(ctx, source, "w1", -time.Second, 1)
+                if err != nil || job == nil {
+                    t.Fatalf("Claim = %+v, %v", job, err)
+                }
+            }
+
+            // the expired leon job is only buried when leon jobs are claimed
+            job, err := q.Claim(ctx, "ligastavok", "w2", time.Minute, 1)
+            if err != nil || job != nil {
+                t.Fatalf("Claim = %+v, %v, want none", job, err)
+            }
+
+            got := states(t, q)
+            if got["leon/running"] != 1 || got["ligastavok/dead"] != 1 {
+                t.Errorf("queue %v, want leon running and ligastavok dead", got)
+            }
+
+            n, err := q.Requeue(ctx, "ligastavok")
+            if err != nil || n != 1 {
+                t.Fatalf("Requeue = %d, %v, want 1", n, err)
+            }
+
+            job, err = q.Claim(ctx, "ligastavok", "w2", time.Minute, 1)
+            if err != nil || job == nil || job.Attempts != 1 {
+                t.Fatalf("Claim = %+v, %v, want the requeued job", job, err)
+            }
+        })
+    }
+}
+
+func TestQueuePurge(t *testing.T) {
+    ctx := context.Background()
+
+    for name, s := range storages(t) {
+        t.Run(name, func(t *testing.T) {
+            q := queueOf(t, s)
+
+            _, err := q.Enqueue(ctx, 0, "leon", "https://leon.ru/", []string{"/1", "/2", "/3"})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            for _, done := range []bool{true, false} {
+                job, err := q.Claim(ctx, "leon", "w1", time.Minute, 3)
+                if err != nil || job == nil {
+                    t.Fatalf("Claim = %+v, %v", job, err)
+                }
+
+                if done {
+                    err = q.Complete(ctx, job)
+                } else {
+                    err = q.Bury(ctx, job, "parse", errors.New("boom"))
+                }
+                if err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            n, err := q.Purge(ctx, time.Now().Add(-time.Hour))
+            if err != nil || n != 0 {
+                t.Fatalf("Purge = %d, %v, want recent jobs kept", n, err)
+            }
+
+            n, err = q.Purge(ctx, time.Now().Add(time.Hour))
+            if err != nil || n != 1 {
+                t.Fatalf("Purge = %d, %v, want the done job deleted", n, err)
+            }
+
+            got := states(t, q)
+            if got["leon/done"] != 0 || got["leon/dead"] != 1 || got["leon/queued"] != 1 {
+                t.Errorf("queue %v, want a dead and a queued job left", got)
+            }
+
+            // the jobs left can still be finished
+            job, err := q.Claim(ctx, "leon", "w1", time.Minute, 3)
+            if err != nil || job == nil || job.URL != "/3" {
+                t.Fatalf("Claim = %+v, %v, want /3", job, err)
+            }
+
+            err = q.Complete(ctx, job)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if got := states(t, q); got["leon/done"] != 1 {
+                t.Errorf("queue %v, want /3 done", got)
+            }
+        })
+    }
+}
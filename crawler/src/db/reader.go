@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Reader is implemented by storages that can list what they stored, it is
+// what export, serve and replay read from
+type Reader interface {
+    ListSnapshots(ctx context.Context, f Filter) ([]Record, error)
+    // ListRuns returns the latest runs first
+    ListRuns(ctx context.Context, limit int) ([]Run, error)
+    // LastRuns returns the latest run of every source by bookmaker code
+    LastRuns(ctx context.Context) (map[string]Run, error)
+    // StoredPage returns what was stored from the match page at url as of
+    // run_id (as of now when 0), nil when it was never stored
+    StoredPage(ctx context.Context, source string, url string, run_id int64) (*StoredPage, error)
+}
+
+// Filter of ListSnapshots, zero fields match everything
+type Filter struct {
+    // Source is a bookmaker code
+    Source string
+    Since time.Time
+    Limit int
+}
+
+// Record is a snapshot together with the game it belongs to
+type Record struct {
+    Snapshot
+    Date time.Time
+    TeamA string
+    TeamB string
+    Tournament string
+}
+
+// listSnapshots is shared by the SQL storages, ordered by capture time
+func listSnapshots(ctx context.Context, conn *sql.DB, f Filter) ([]Record, error) {
+    query := `SELECT g.game_id, g.date, g.radiant, g.dire, coalesce(g.tournament, ''),
+        m.name, m.kind, o.label, o.ordinal, s.price, s.suspended,
+        coalesce(s.raw_value, ''), coalesce(s.source, ''), coalesce(s.run_id, 0), s.captured_at
+    FROM odds_snapshots s
+    JOIN outcomes o ON o.outcome_id=s.outcome_id
+    JOIN markets m ON m.market_id=o.market_id
+    JOIN games g ON g.game_id=m.game_id
+    WHERE ($1 = '' OR g.source=$1) AND s.captured_at >= $2
+    ORDER BY s.captured_at, s.snapshot_id`
+
+    if f.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", f.Limit)
+    }
+
+    rows, err := conn.QueryContext(ctx, query + ";", f.Source, f.Since.UTC())
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var res []Record
+
+    for rows.Next() {
+        var r Record
+        var date sql.NullTime
+
+        err = rows.Scan(
+            &r.GameID,
+            &date,
+            &r.TeamA,
+            &r.TeamB,
+            &r.Tournament,
+            &r.Market,
+            &r.Kind,
+            &r.Outcome,
+            &r.Ordinal,
+            &r.Price,
+            &r.Suspended,
+            &r.Value,
+            &r.Source,
+            &r.RunID,
+            &r.CapturedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+
+        r.Date = date.Time
+        res = append(res, r)
+    }
+
+    return res, rows.Err()
+}
+
+const runColumns = `run_id, source, coalesce(start_url, ''), started_at, finished_at, status,
+    coalesce(error, ''), pages, failed, errors`
+
+func listRuns(ctx context.Context, conn *sql.DB, limit int) ([]Run, error) {
+    query := "SELECT " + runColumns + " FROM crawl_runs ORDER BY run_id DESC"
+
+    if limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", limit)
+    }
+
+    rows, err := conn.QueryContext(ctx, query + ";")
+    if err != nil {
+        return nil, err
+    }
+
+    return scanRuns(rows)
+}
+
+func lastRuns(ctx context.Context, conn *sql.DB) (map[string]Run, error) {
+    rows, err := conn.QueryContext(
+        ctx,
+        "SELECT " + runColumns + ` FROM crawl_runs
+        WHERE run_id IN (SELECT max(run_id) FROM crawl_runs GROUP BY source);`,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    runs, err := scanRuns(rows)
+    if err != nil {
+        return nil, err
+    }
+
+    res := map[string]Run{}
+    for _, r := range runs {
+        res[r.Source] = r
+    }
+
+    return res, nil
+}
+
+// scanRuns reads rows of runColumns and closes them
+func scanRuns(rows *sql.Rows) ([]Run, error) {
+    defer rows.Close()
+
+    var res []Run
+
+    for rows.Next() {
+        var r Run
+        var finished sql.NullTime
+        var errs string
+
+        err := rows.Scan(
+            &r.ID,
+            &r.Source,
+            &r.StartURL,
+            &r.StartedAt,
+            &finished,
+            &r.Status,
+            &r.Error,
+            &r.Stats.Pages,
+            &r.Stats.Failed,
+            &errs,
+        )
+        if err != nil {
+            return nil, err
+        }
+
+        r.FinishedAt = finished.Time
+
+        err = json.Unmarshal([]byte(errs), &r.Stats.Errors)
+        if err != nil {
+            return nil, err
+        }
+
+        res = append(res, r)
+    }
+
+    return res, rows.Err()
+}
+
+func (db *DB) ListSnapshots(ctx context.Context, f Filter) ([]Record, error) {
+    return listSnapshots(ctx, db.db, f)
+}
+
+func (db *DB) ListRuns(ctx context.Context, limit int) ([]Run, error) {
+    return listRuns(ctx, db.db, limit)
+}
+
+func (db *DB) LastRuns(ctx context.Context) (map[string]Run, error) {
+    return lastRuns(ctx, db.db)
+}
+
+func (db *SQLiteDB) ListSnapshots(ctx context.Context, f Filter) ([]Record, error) {
+    return listSnapshots(ctx, db.db, f)
+}
+
+func (db *SQLiteDB) ListRuns(ctx context.Context, limit int) ([]Run, error) {
+    return listRuns(ctx, db.db, limit)
+}
+
+func (db *SQLiteDB) LastRuns(ctx context.Context) (map[string]Run, error) {
+    return lastRuns(ctx, db.db)
+}
+
+func (db *MemoryDB) ListSnapshots(ctx context.Context, f Filter) ([]Record, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    var res []Record
+
+    for _, s := range db.snapshots {
+        g := db.games[s.GameID - 1]
+
+        if (len(f.Source) > 0 && g.Source != f.Source) || s.CapturedAt.Before(f.Since) {
+            continue
+        }
+
+        res = append(res, Record{
+            Snapshot: s,
+            Date: g.Date,
+            TeamA: g.TeamA,
+            TeamB: g.TeamB,
+            Tournament: g.Tournament,
+        })
+    }
+
+    sort.SliceStable(res, func(i, j int) bool {
+        return res[i].CapturedAt.Before(res[j].CapturedAt)
+    })
+
+    if f.Limit > 0 && len(res) > f.Limit {
+        res = res[:f.Limit]
+    }
+
+    return res, nil
+}
+
+func (db *MemoryDB) ListRuns(ctx context.Context, limit int) ([]Run, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    var res []Run
+
+    for i := len(db.runs) - 1; i >= 0 && (limit <= 0 || len(res) < limit); i-- {
+        res = append(res, db.runs[i])
+    }
+
+    return res, nil
+}
+
+func (db *MemoryDB) LastRuns(ctx context.Context) (map[string]Run, error) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+
+    res := map[string]Run{}
+    for _, r := range db.runs {
+        res[r.Source] = r
+    }
+
+    return res, nil
+}
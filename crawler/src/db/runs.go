@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+)
+
+const (
+    RunRunning = "running"
+    RunDone = "done"
+    RunFailed = "failed"
+    // the run was interrupted (shutdown or run timeout)
+    RunCancelled = "cancelled"
+)
+
+type Run struct {
+    ID int64 `json:"id"`
+    Source string `json:"source"`
+    StartURL string `json:"start_url"`
+    StartedAt time.Time `json:"started_at"`
+    FinishedAt time.Time `json:"finished_at"`
+    Status string `json:"status"`
+    Error string `json:"error,omitempty"`
+    Stats RunStats `json:"stats"`
+}
+
+// RunStats counts the match pages of a run (the listing is not counted),
+// Errors holds failed attempts by class of error (see retry.Class) including
+// the ones that succeeded on a retry and those of loading the listing
+type RunStats struct {
+    Pages int `json:"pages"`
+    Failed int `json:"failed"`
+    Errors map[string]int `json:"errors"`
+}
+
+// startRun registers the bookmaker (so sources added in code need no
+// migration) and opens a crawl run for it. Shared by the SQL storages
+func startRun(ctx context.Context, conn *sql.DB, source bookmaker.Bookmaker, start_url string) (int64, error) {
+    var run_id int64
+
+    _, err := conn.ExecContext(
+        ctx,
+        `INSERT INTO bookmakers (code, name, base_url) VALUES ($1, $2, $3)
+        ON CONFLICT (code) DO UPDATE SET name=excluded.name, base_url=excluded.base_url;`,
+        source.Code,
+        source.Name,
+        source.BaseURL,
+    )
+    if err != nil {
+        return run_id, err
+    }
+
+    err = conn.QueryRowContext(
+        ctx,
+        `INSERT INTO crawl_runs (source, start_url, started_at, status)
+        VALUES ($1, $2, $3, $4) RETURNING run_id;`,
+        source.Code,
+        start_url,
+        time.Now().UTC(),
+        RunRunning,
+    ).Scan(&run_id)
+
+    return run_id, err
+}
+
+func finishRun(ctx context.Context, conn *sql.DB, run_id int64, stats RunStats, runErr error) error {
+    status := RunStatus(runErr)
+    msg := sql.NullString{}
+
+    if runErr != nil {
+        msg = sql.NullString{String: runErr.Error(), Valid: true}
+    }
+
+    errs := stats.Errors
+    if errs == nil {
+        errs = map[string]int{}
+    }
+
+    encoded, err := json.Marshal(errs)
+    if err != nil {
+        return err
+    }
+
+    _, err = conn.ExecContext(
+        ctx,
+        `UPDATE crawl_runs SET finished_at=$1, status=$2, error=$3, pages=$4, failed=$5, errors=$6
+        WHERE run_id=$7;`,
+        time.Now().UTC(),
+        status,
+        msg,
+        stats.Pages,
+        stats.Failed,
+        string(encoded),
+        run_id,
+    )
+
+    return err
+}
+
+// RunStatus is the status a run that ended with runErr is closed with, a
+// run that hit its timeout counts as cancelled
+func RunStatus(runErr error) string {
+    switch {
+    case runErr == nil:
+        return RunDone
+    case errors.Is(runErr, context.Canceled), errors.Is(runErr, context.DeadlineExceeded):
+        return RunCancelled
+    default:
+        return RunFailed
+    }
+}
+
+// nullable run id, games saved outside of a run (e.g. tests) have none
+func runID(id int64) sql.NullInt64 {
+    return sql.NullInt64{Int64: id, Valid: id != 0}
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/resolve"
+	"mxshs/crawler/src/teams"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDB is a single file storage for running the crawler locally.
+// sqlite numbers $N parameters in the order they first appear in a query, so
+// queries it runs must use $1, $2, ... in that order to bind the right values
+type SQLiteDB struct {
+    db *sql.DB
+    resolver *resolve.Resolver
+}
+
+func GetSQLiteDB(path string) (*SQLiteDB, error) {
+    if len(path) == 0 {
+        return nil, fmt.Errorf("[ERROR] SQLite storage requires a database path (db.path or DB_PATH)")
+    }
+
+    conn, err := sql.Open("sqlite3", path + "?_foreign_keys=on&_busy_timeout=5000")
+    if err != nil {
+        return nil, err
+    }
+
+    // sqlite does not handle concurrent writers, parser goroutines queue on one connection
+    conn.SetMaxOpenConns(1)
+
+    return &SQLiteDB{db: conn, resolver: resolve.GetResolver()}, nil
+}
+
+func (db *SQLiteDB) Migrate() error {
+    return db.migrator().Up()
+}
+
+func (db *SQLiteDB) MigrateDown(steps int) error {
+    return db.migrator().Down(steps)
+}
+
+func (db *SQLiteDB) MigrationStatus() ([]MigrationStatus, error) {
+    return db.migrator().Status()
+}
+
+func (db *SQLiteDB) migrator() *migrator {
+    return &migrator{db: db.db, dialect: "sqlite"}
+}
+
+func (db *SQLiteDB) StartRun(ctx context.Context, source bookmaker.Bookmaker, start_url string) (int64, error) {
+    return startRun(ctx, db.db, source, start_url)
+}
+
+func (db *SQLiteDB) FinishRun(ctx context.Context, run_id int64, stats RunStats, runErr error) error {
+    return finishRun(ctx, db.db, run_id, stats, runErr)
+}
+
+func (db *SQLiteDB) LoadTeams() ([]teams.Team, error) {
+    return loadTeams(db.db)
+}
+
+func (db *SQLiteDB) SaveUnknownTeams(unknown []teams.Suggestion) error {
+    return saveUnknownTeams(db.db, unknown)
+}
+
+func (db *SQLiteDB) PendingTeams() ([]teams.Suggestion, error) {
+    return pendingTeams(db.db)
+}
+
+func (db *SQLiteDB) AddTeam(name string) error {
+    return addTeamTx(db.db, name)
+}
+
+func (db *SQLiteDB) ApproveAlias(alias, team string) error {
+    return approveAlias(db.db, alias, team)
+}
+
+func (db *SQLiteDB) Close() error {
+    return db.db.Close()
+}
+
+func (db *SQLiteDB) SaveGameBets(ctx context.Context, game *domain.GameBets) (int, error) {
+    return db.save(ctx, game, false)
+}
+
+func (db *SQLiteDB) ReplayGameBets(ctx context.Context, game *domain.GameBets) (int, error) {
+    return db.save(ctx, game, true)
+}
+
+func (db *SQLiteDB) save(ctx context.Context, game *domain.GameBets, replay bool) (int, error) {
+    var game_id int
+
+    tx, err := db.db.BeginTx(ctx, nil)
+    if err != nil {
+        return game_id, err
+    }
+    defer tx.Rollback()
+
+    err = tx.QueryRowContext(
+        ctx,
+        upsertGameSQL(replay),
+        game.Date,
+        game.Tournament,
+        game.TeamA,
+        game.TeamB,
+        game.Source,
+        game.SourceURL,
+        runID(game.RunID),
+        crawledAt(game, replay),
+    ).Scan(&game_id)
+    if err != nil {
+        return game_id, err
+    }
+
+    err = linkEvent(ctx, tx, db.resolver, game_id, game)
+    if err != nil {
+        return game_id, err
+    }
+
+    for _, bet := range game.Bets {
+        var market_id int
+
+        kind, map_no, line := marketKind(bet)
+
+        err = tx.QueryRowContext(
+            ctx,
+            `INSERT INTO markets (game_id, name, kind, map_no, line) VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT (game_id, name) DO UPDATE
+            SET kind=excluded.kind, map_no=excluded.map_no, line=excluded.line
+            RETURNING market_id;`,
+            game_id,
+            bet.Type,
+            kind,
+            map_no,
+            line,
+        ).Scan(&market_id)
+        if err != nil {
+            return game_id, err
+        }
+
+        for i, opt := range bet.Opts {
+            var outcome_id int
+
+            err = tx.QueryRowContext(
+                ctx,
+                `INSERT INTO outcomes (market_id, label, ordinal) VALUES ($1, $2, $3)
+                ON CONFLICT (market_id, label) DO UPDATE SET ordinal=excluded.ordinal
+                RETURNING outcome_id;`,
+                market_id,
+                opt.Name,
+                i,
+            ).Scan(&outcome_id)
+            if err != nil {
+                return game_id, err
+            }
+
+            _, err = tx.ExecContext(
+                ctx,
+                `INSERT INTO odds_snapshots (outcome_id, price, suspended, raw_value, source, captured_at, run_id)
+                SELECT $1, $2, $3, $4, $5, $6, $7
+                WHERE $4 IS NOT (
+                    SELECT raw_value FROM odds_snapshots
+                    WHERE outcome_id=$1 AND captured_at <= $6
+                    ORDER BY captured_at DESC LIMIT 1
+                )
+                ON CONFLICT (outcome_id, captured_at) DO NOTHING;`,
+                outcome_id,
+                optionPrice(opt),
+                opt.Suspended,
+                opt.Value,
+                opt.Source,
+                opt.CapturedAt.UTC(),
+                runID(game.RunID),
+            )
+            if err != nil {
+                return game_id, err
+            }
+        }
+    }
+
+    return game_id, tx.Commit()
+}
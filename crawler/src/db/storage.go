@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/markets"
+)
+
+// Storage is what parsers persist scraped matches through
+type Storage interface {
+    // SaveGameBets atomically stores a game with all of its bets, returns game_id.
+    // Nothing is stored when ctx is cancelled before the commit
+    SaveGameBets(ctx context.Context, game *domain.GameBets) (int, error)
+    // StartRun opens a crawl run for the bookmaker, FinishRun closes it with
+    // its page counts, as failed when runErr is not nil (cancelled when it is
+    // a context error)
+    StartRun(ctx context.Context, source bookmaker.Bookmaker, start_url string) (int64, error)
+    FinishRun(ctx context.Context, run_id int64, stats RunStats, runErr error) error
+    // MatchStates returns the stored start and last crawl time of the
+    // matches of source at urls, urls never stored are left out
+    MatchStates(ctx context.Context, source string, urls []string) (map[string]MatchState, error)
+    TeamStore
+    // Migrate brings the schema to the version embedded in the binary
+    Migrate() error
+    Close() error
+}
+
+// Replayer is implemented by storages that take games replayed from the
+// archive. Unlike SaveGameBets a stored game keeps the run and crawl time of
+// its last crawl, and an odds snapshot is only kept when its value differs
+// from the one current when the page was captured
+type Replayer interface {
+    ReplayGameBets(ctx context.Context, game *domain.GameBets) (int, error)
+}
+
+// Migrator is implemented by storages backed by a versioned SQL schema
+type Migrator interface {
+    Migrate() error
+    MigrateDown(steps int) error
+    MigrationStatus() ([]MigrationStatus, error)
+}
+
+type Snapshot struct {
+    GameID int
+    Market string
+    Kind string
+    Outcome string
+    Ordinal int
+    Price sql.NullFloat64
+    Suspended bool
+    Value string
+    Source string
+    RunID int64
+    CapturedAt time.Time
+}
+
+// upsertGameSQL stores a game under its (source, radiant, date) key and
+// returns its game_id. A replayed game keeps the run_id and crawled_at of
+// the stored one. DO UPDATE instead of DO NOTHING so that RETURNING always
+// yields the id, on Postgres it also keeps the game row locked until commit,
+// which serializes concurrent writers of the same match
+func upsertGameSQL(replay bool) string {
+    keep := ", run_id=excluded.run_id, crawled_at=excluded.crawled_at"
+    if replay {
+        keep = ""
+    }
+
+    return `INSERT INTO games (date, tournament, radiant, dire, source, source_url, run_id, crawled_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (source, radiant, date) DO UPDATE
+        SET tournament=excluded.tournament, dire=excluded.dire, source_url=excluded.source_url` + keep + `
+        RETURNING game_id;`
+}
+
+// crawledAt of a game being saved: now, or for a replayed page the time it
+// was captured, which its odds carry
+func crawledAt(game *domain.GameBets, replay bool) time.Time {
+    var at time.Time
+
+    if replay {
+        for _, bet := range game.Bets {
+            for _, opt := range bet.Opts {
+                if opt.CapturedAt.After(at) {
+                    at = opt.CapturedAt
+                }
+            }
+        }
+    }
+
+    if at.IsZero() {
+        at = time.Now()
+    }
+
+    return at.UTC()
+}
+
+// price of an option as stored, NULL for suspended or unparseable values
+func optionPrice(opt domain.Option) sql.NullFloat64 {
+    if opt.Suspended || opt.Price == 0 {
+        return sql.NullFloat64{}
+    }
+
+    return sql.NullFloat64{Float64: opt.Price, Valid: true}
+}
+
+// canonical kind, map and line of a market as stored, NULL when not set
+func marketKind(bet domain.Bet) (string, sql.NullInt64, sql.NullFloat64) {
+    kind := bet.Kind
+    if len(kind) == 0 {
+        kind = markets.Other
+    }
+
+    var map_no sql.NullInt64
+    if bet.Map > 0 {
+        map_no = sql.NullInt64{Int64: int64(bet.Map), Valid: true}
+    }
+
+    var line sql.NullFloat64
+    if bet.Line != nil {
+        line = sql.NullFloat64{Float64: *bet.Line, Valid: true}
+    }
+
+    return kind, map_no, line
+}
+
+// GetStorage opens the storage selected by cfg.Driver (postgres by default,
+// sqlite or memory)
+func GetStorage(cfg Config) (Storage, error) {
+    switch cfg.Driver {
+    case "", "postgres":
+        return GetDB(cfg)
+    case "sqlite":
+        return GetSQLiteDB(cfg.Path)
+    case "memory":
+        return GetMemoryDB(), nil
+    default:
+        return nil, fmt.Errorf("[ERROR] Unknown storage driver: %s", cfg.Driver)
+    }
+}
+
+// OpenSink opens the storage named by spec: postgres, sqlite (at cfg.Path),
+// sqlite:<path>, memory, jsonl (stdout) or jsonl:<path>. An empty spec is
+// the storage selected by cfg.Driver
+func OpenSink(spec string, cfg Config) (Storage, error) {
+    kind, arg, err := ParseSink(spec)
+    if err != nil {
+        return nil, err
+    }
+
+    switch kind {
+    case "postgres":
+        return GetDB(cfg)
+    case "sqlite":
+        if len(arg) == 0 {
+            arg = cfg.Path
+        }
+        return GetSQLiteDB(arg)
+    case "memory":
+        return GetMemoryDB(), nil
+    case "jsonl":
+        if len(arg) == 0 {
+            arg = "-"
+        }
+        return GetJSONLSink(arg)
+    default:
+        return GetStorage(cfg)
+    }
+}
+
+// ParseSink splits a sink spec into its kind and the optional argument after
+// the colon, an empty kind stands for the configured driver
+func ParseSink(spec string) (kind string, arg string, err error) {
+    kind, arg, _ = strings.Cut(spec, ":")
+
+    switch kind {
+    case "", "postgres", "sqlite", "memory", "jsonl":
+        return kind, arg, nil
+    default:
+        return "", "", fmt.Errorf("[ERROR] Unknown sink: %s (expected postgres, sqlite[:path], memory or jsonl[:path])", spec)
+    }
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"mxshs/crawler/src/teams"
+)
+
+// TeamStore keeps the team alias registry
+type TeamStore interface {
+    LoadTeams() ([]teams.Team, error)
+    // SaveUnknownTeams records names no team matched for review
+    SaveUnknownTeams(unknown []teams.Suggestion) error
+    PendingTeams() ([]teams.Suggestion, error)
+    AddTeam(name string) error
+    // ApproveAlias makes alias a spelling of team, creating the team if needed
+    ApproveAlias(alias, team string) error
+}
+
+func loadTeams(conn *sql.DB) ([]teams.Team, error) {
+    rows, err := conn.Query(
+        `SELECT t.name, a.alias
+        FROM teams t
+        LEFT JOIN team_aliases a ON a.team_id=t.team_id
+        ORDER BY t.name, a.alias;`,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var res []teams.Team
+
+    for rows.Next() {
+        var name string
+        var alias sql.NullString
+
+        err = rows.Scan(&name, &alias)
+        if err != nil {
+            return nil, err
+        }
+
+        if len(res) == 0 || res[len(res) - 1].Name != name {
+            res = append(res, teams.Team{Name: name})
+        }
+
+        if alias.Valid {
+            t := &res[len(res) - 1]
+            t.Aliases = append(t.Aliases, alias.String)
+        }
+    }
+
+    return res, rows.Err()
+}
+
+func saveUnknownTeams(conn *sql.DB, unknown []teams.Suggestion) error {
+    tx, err := conn.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    for _, s := range unknown {
+        _, err = tx.Exec(
+            `INSERT INTO team_aliases (alias, suggested_team_id, score, source, first_seen, last_seen)
+            VALUES ($1, (SELECT team_id FROM teams WHERE name=$2), $3, $4, $5, $5)
+            ON CONFLICT (alias) DO UPDATE
+            SET suggested_team_id=excluded.suggested_team_id, score=excluded.score,
+                last_seen=excluded.last_seen
+            WHERE team_aliases.team_id IS NULL;`,
+            s.Alias,
+            s.Team,
+            s.Score,
+            s.Source,
+            s.SeenAt,
+        )
+        if err != nil {
+            return err
+        }
+    }
+
+    return tx.Commit()
+}
+
+func pendingTeams(conn *sql.DB) ([]teams.Suggestion, error) {
+    rows, err := conn.Query(
+        `SELECT a.alias, coalesce(t.name, ''), coalesce(a.score, 0), coalesce(a.source, ''), a.last_seen
+        FROM team_aliases a
+        LEFT JOIN teams t ON t.team_id=a.suggested_team_id
+        WHERE a.team_id IS NULL
+        ORDER BY a.alias;`,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var res []teams.Suggestion
+
+    for rows.Next() {
+        s := teams.Suggestion{}
+
+        err = rows.Scan(&s.Alias, &s.Team, &s.Score, &s.Source, &s.SeenAt)
+        if err != nil {
+            return nil, err
+        }
+
+        res = append(res, s)
+    }
+
+    return res, rows.Err()
+}
+
+func addTeam(tx *sql.Tx, name string) (int64, error) {
+    var team_id int64
+
+    err := tx.QueryRow(
+        `INSERT INTO teams (name) VALUES ($1)
+        ON CONFLICT (name) DO UPDATE SET name=excluded.name
+        RETURNING team_id;`,
+        name,
+    ).Scan(&team_id)
+    if err != nil {
+        return team_id, err
+    }
+
+    // a pending alias spelled exactly like the new team is settled by it
+    _, err = tx.Exec(
+        `UPDATE team_aliases SET team_id=$1 WHERE alias=$2 AND team_id IS NULL;`,
+        team_id,
+        name,
+    )
+
+    return team_id, err
+}
+
+func addTeamTx(conn *sql.DB, name string) error {
+    tx, err := conn.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    _, err = addTeam(tx, name)
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+func approveAlias(conn *sql.DB, alias, team string) error {
+    if len(alias) == 0 || len(team) == 0 {
+        return fmt.Errorf("[ERROR] Both alias and team are required")
+    }
+
+    tx, err := conn.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    team_id, err := addTeam(tx, team)
+    if err != nil {
+        return err
+    }
+
+    now := time.Now().UTC()
+
+    _, err = tx.Exec(
+        `INSERT INTO team_aliases (alias, team_id, first_seen, last_seen)
+        VALUES ($1, $2, $3, $3)
+        ON CONFLICT (alias) DO UPDATE SET team_id=excluded.team_id;`,
+        alias,
+        team_id,
+        now,
+    )
+    if err != nil {
+        return err
+    }
+
+    err = renameTeam(tx, alias, team)
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+// renameTeam gives stored games and events the canonical name of a team that
+// was stored under an alias until now. Games are keyed by (source, radiant,
+// date), so without the rename the next crawl of the match would store it a
+// second time under the new name. A game whose renamed key is taken already
+// (crawled again before the alias was approved) keeps its name
+func renameTeam(tx *sql.Tx, alias, team string) error {
+    if alias == team {
+        return nil
+    }
+
+    statements := []string{
+        `UPDATE games SET radiant=$1 WHERE radiant=$2
+        AND NOT EXISTS (
+            SELECT 1 FROM games g WHERE g.source=games.source AND g.radiant=$1 AND g.date=games.date
+        );`,
+        `UPDATE games SET dire=$1 WHERE dire=$2;`,
+        `UPDATE events SET team_a=$1 WHERE team_a=$2;`,
+        `UPDATE events SET team_b=$1 WHERE team_b=$2;`,
+    }
+
+    for _, stmt := range statements {
+        _, err := tx.Exec(stmt, team, alias)
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
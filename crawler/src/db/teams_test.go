@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// a game stored under a team name before its alias was approved is updated
+// by the next crawl, which stores the canonical name, instead of duplicated
+func TestApproveAliasRenamesGames(t *testing.T) {
+    ctx := context.Background()
+
+    for name, store := range storages(t) {
+        t.Run(name, func(t *testing.T) {
+            before, err := store.SaveGameBets(ctx, testGame("leon", "Spirit", "1.50", matchStart.Add(-2 * time.Hour)))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            err = store.ApproveAlias("Spirit", "Team Spirit")
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            after, err := store.SaveGameBets(ctx, testGame("leon", "Team Spirit", "1.55", matchStart.Add(-time.Hour)))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if after != before {
+                t.Errorf("the match was stored again as game %d, want game %d", after, before)
+            }
+
+            // the alias of the other team in an event is renamed as well
+            err = store.ApproveAlias("OG", "OG Esports")
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            game := testGame("leon", "Team Spirit", "1.60", matchStart.Add(-30 * time.Minute))
+            game.TeamB = "OG Esports"
+
+            again, err := store.SaveGameBets(ctx, game)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if again != before {
+                t.Errorf("renaming team b stored the match again as game %d, want game %d", again, before)
+            }
+        })
+    }
+}
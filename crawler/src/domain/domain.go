@@ -3,20 +3,35 @@ package domain
 import "time"
 
 type GameBets struct {
-    TeamA string
-    TeamB string
-    Date time.Time
-    Tournament string
-    Bets []Bet
+    TeamA string `json:"team_a"`
+    TeamB string `json:"team_b"`
+    Date time.Time `json:"date"`
+    Tournament string `json:"tournament"`
+    Bets []Bet `json:"bets"`
+    // Source is the bookmaker code the match was scraped from
+    Source string `json:"source"`
+    SourceURL string `json:"source_url"`
+    RunID int64 `json:"run_id,omitempty"`
 }
 
 type Bet struct {
-    Type string
-    Opts []Option
+    // Type is the market title as shown on the page, Kind, Map and Line are
+    // the canonical market it was classified as (see src/markets)
+    Type string `json:"type"`
+    Kind string `json:"kind,omitempty"`
+    Map int `json:"map,omitempty"`
+    Line *float64 `json:"line,omitempty"`
+    Opts []Option `json:"options"`
 }
 
+// Option is a single outcome price as seen on a bookmaker page at CapturedAt
 type Option struct {
-    Name string
-    Value string
+    Name string `json:"name"`
+    // Value is the coefficient exactly as shown on the page, Price is the
+    // same in decimal odds (0 when it could not be parsed)
+    Value string `json:"value"`
+    Price float64 `json:"price,omitempty"`
+    Suspended bool `json:"suspended,omitempty"`
+    Source string `json:"source"`
+    CapturedAt time.Time `json:"captured_at"`
 }
-
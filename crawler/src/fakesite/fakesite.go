@@ -0,0 +1,163 @@
+package fakesite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page is a scripted response of a fake site
+type Page struct {
+    // Status of the response, 200 when 0. Other statuses are sent with a
+    // plain error body
+    Status int
+    // Header is added to the response, e.g. cf-mitigated to look like bot
+    // protection
+    Header http.Header
+    // HTML is rendered into the body by a script, Delay after the page
+    // loaded like the single page apps of the real sites do, or while it
+    // loads when Delay is 0
+    HTML string
+    Delay time.Duration
+    // Reveal is rendered once an element matching the Click selector (part
+    // of HTML) was clicked, like a tab that loads more markets
+    Click string
+    Reveal string
+    // Omit lists selectors removed right after rendering, to play a layout
+    // change or content that never shows up
+    Omit []string
+}
+
+// Server serves fake bookmaker sites on the loopback. Every site gets its own
+// host, <code>.localhost, which Chrome resolves to the loopback by itself, so
+// the parsers can tell the sites apart just like the real ones once their
+// base urls are overridden (see bookmaker.Override)
+type Server struct {
+    srv *httptest.Server
+
+    mu sync.Mutex
+    pages map[string][]Page
+    hits map[string]int
+}
+
+func Start() *Server {
+    s := &Server{pages: map[string][]Page{}, hits: map[string]int{}}
+    s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+
+    return s
+}
+
+func (s *Server) Close() {
+    s.srv.Close()
+}
+
+// URL of a site, e.g. http://leon.localhost:41234
+func (s *Server) URL(site string) string {
+    _, port, _ := net.SplitHostPort(s.srv.Listener.Addr().String())
+
+    return fmt.Sprintf("http://%s.localhost:%s", site, port)
+}
+
+// Handle scripts the responses to path on site: every request gets the next
+// page, the last one is repeated. E.g. a 503 followed by a page makes the
+// first attempt fail and the retry succeed
+func (s *Server) Handle(site string, path string, pages ...Page) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.pages[site + " " + path] = pages
+}
+
+// Hits counts the requests made for path on site
+func (s *Server) Hits(site string, path string) int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.hits[site + " " + path]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+    host, _, err := net.SplitHostPort(r.Host)
+    if err != nil {
+        host = r.Host
+    }
+
+    key := strings.TrimSuffix(host, ".localhost") + " " + r.URL.Path
+
+    s.mu.Lock()
+    pages, ok := s.pages[key]
+    s.hits[key]++
+    n := s.hits[key]
+    s.mu.Unlock()
+
+    if !ok || len(pages) == 0 {
+        http.NotFound(w, r)
+        return
+    }
+
+    page := pages[min(n, len(pages)) - 1]
+
+    for name, values := range page.Header {
+        for _, v := range values {
+            w.Header().Add(name, v)
+        }
+    }
+
+    if page.Status != 0 && page.Status != http.StatusOK {
+        http.Error(w, http.StatusText(page.Status), page.Status)
+        return
+    }
+
+    script, err := json.Marshal(map[string]any{
+        "html": page.HTML,
+        "delay": page.Delay.Milliseconds(),
+        "click": page.Click,
+        "reveal": page.Reveal,
+        "omit": append([]string{}, page.Omit...),
+    })
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    fmt.Fprintf(w, shell, script)
+}
+
+// shell renders the page from script data, json.Marshal escapes the HTML so
+// it can not close the script tag
+const shell = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>fake</title></head>
+<body>
+<script>
+const page = %s;
+
+function render(html) {
+    document.body.insertAdjacentHTML('beforeend', html);
+    page.omit.forEach(s => document.querySelectorAll(s).forEach(e => e.remove()));
+}
+
+function load() {
+    render(page.html);
+
+    if (page.click) {
+        document.querySelectorAll(page.click).forEach(e => e.addEventListener('click', () => render(page.reveal), {once: true}));
+    }
+}
+
+// without a delay the content is there by the time the page loaded
+if (page.delay > 0) {
+    setTimeout(load, page.delay);
+} else {
+    load();
+}
+</script>
+</body>
+</html>
+`
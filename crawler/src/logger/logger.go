@@ -1,13 +0,0 @@
-package logger
-
-import (
-    "log"
-	"os"
-)
-        
-var Logger *log.Logger
-
-func init() {
-    Logger = log.New(os.Stdout, "crawler ", log.LstdFlags)
-}
-
@@ -0,0 +1,95 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Level of a log line, lines below the configured level are dropped
+type Level int
+
+const (
+    Debug Level = iota
+    Info
+    Error
+)
+
+var (
+    mu sync.Mutex
+    level = Info
+    // logs go to stderr, so that commands printing data to stdout can be piped
+    out io.Writer = os.Stderr
+)
+
+// ParseLevel accepts debug, info and error
+func ParseLevel(s string) (Level, error) {
+    switch strings.ToLower(strings.TrimSpace(s)) {
+    case "debug":
+        return Debug, nil
+    case "", "info":
+        return Info, nil
+    case "error":
+        return Error, nil
+    default:
+        return Info, fmt.Errorf("[ERROR] Unknown log level %q (expected debug, info or error)", s)
+    }
+}
+
+func SetLevel(l Level) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    level = l
+}
+
+func SetOutput(w io.Writer) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    out = w
+}
+
+func Debugf(format string, args ...any) {
+    write(Debug, "[DEBUG] ", format, args...)
+}
+
+func Infof(format string, args ...any) {
+    write(Info, "[INFO] ", format, args...)
+}
+
+func Errorf(format string, args ...any) {
+    write(Error, "[ERROR] ", format, args...)
+}
+
+// Err logs an error, most errors of the crawler already carry the prefix
+func Err(err error) {
+    if err == nil {
+        return
+    }
+
+    msg := err.Error()
+    if !strings.HasPrefix(msg, "[") {
+        msg = "[ERROR] " + msg
+    }
+
+    write(Error, "", "%s", msg)
+}
+
+func write(l Level, prefix string, format string, args ...any) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    if l < level {
+        return
+    }
+
+    line := prefix + fmt.Sprintf(format, args...)
+    if !strings.HasSuffix(line, "\n") {
+        line += "\n"
+    }
+
+    io.WriteString(out, line)
+}
@@ -0,0 +1,237 @@
+package markets
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"mxshs/crawler/src/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+    MatchWinner = "match_winner"
+    MapWinner = "map_winner"
+    MatchHandicap = "match_handicap"
+    MapHandicap = "map_handicap"
+    TotalMaps = "total_maps"
+    MapTotalKills = "map_total_kills"
+    MapDuration = "map_duration"
+    CorrectScore = "correct_score"
+    FirstBlood = "first_blood"
+    FirstTower = "first_tower"
+    FirstRoshan = "first_roshan"
+    // Other is stored for titles no rule matched
+    Other = "other"
+)
+
+//go:embed rules/default.yaml
+var defaultRules embed.FS
+
+// Rule maps market titles matching Title to Kind, see rules/default.yaml
+type Rule struct {
+    Kind string `yaml:"kind"`
+    Title string `yaml:"title"`
+    RequiresMap bool `yaml:"requires_map"`
+    LineFromOutcomes bool `yaml:"line_from_outcomes"`
+
+    re *regexp.Regexp
+}
+
+type ruleFile struct {
+    Rules []Rule `yaml:"rules"`
+}
+
+// Market is the canonical form of a bookmaker market title
+type Market struct {
+    Kind string
+    Map int
+    Line *float64
+}
+
+var (
+    // "карта 2", "map 2", "2-я карта", "2nd map". Numbers with a decimal
+    // part are matched whole so "Тотал карт 2.5" is not read as map 2
+    mapRe = regexp.MustCompile(
+        `(?i)(?:карт[аеуы]?|map)\s*№?\s*(\d+(?:[.,]\d+)?)|(\d+(?:[.,]\d+)?)\s*-?\s*(?:я|й|ой|ая|st|nd|rd|th)?\s+(?:карт|map)`,
+    )
+    // a trailing number, optionally in parentheses: "Больше (2.5)", "Team -1,5"
+    lineRe = regexp.MustCompile(`\(?\s*([+-]?\d+(?:[.,]\d+)?)\s*\)?\s*$`)
+)
+
+type Classifier struct {
+    rules []Rule
+}
+
+var (
+    defaultOnce sync.Once
+    defaultClassifier *Classifier
+)
+
+// Default returns the classifier with the built in rules only
+func Default() *Classifier {
+    defaultOnce.Do(func() {
+        c, err := Load()
+        if err != nil {
+            panic(err)
+        }
+
+        defaultClassifier = c
+    })
+
+    return defaultClassifier
+}
+
+// Load builds a classifier from rule files, their rules are tried in the
+// order of the files and before the built in ones
+func Load(paths ...string) (*Classifier, error) {
+    c := &Classifier{}
+
+    for _, p := range paths {
+        p = strings.TrimSpace(p)
+        if len(p) == 0 {
+            continue
+        }
+
+        data, err := os.ReadFile(p)
+        if err != nil {
+            return nil, err
+        }
+
+        err = c.add(p, data)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    data, err := defaultRules.ReadFile("rules/default.yaml")
+    if err != nil {
+        return nil, err
+    }
+
+    err = c.add("rules/default.yaml", data)
+    if err != nil {
+        return nil, err
+    }
+
+    return c, nil
+}
+
+func (c *Classifier) add(name string, data []byte) error {
+    var f ruleFile
+
+    err := yaml.Unmarshal(data, &f)
+    if err != nil {
+        return fmt.Errorf("[ERROR] Failed to read market rules %s: %s", name, err.Error())
+    }
+
+    for i, r := range f.Rules {
+        if len(r.Kind) == 0 || len(r.Title) == 0 {
+            return fmt.Errorf("[ERROR] Market rule %d in %s needs both kind and title", i + 1, name)
+        }
+
+        r.re, err = regexp.Compile(r.Title)
+        if err != nil {
+            return fmt.Errorf("[ERROR] Bad title pattern in market rule %d in %s: %s", i + 1, name, err.Error())
+        }
+
+        c.rules = append(c.rules, r)
+    }
+
+    return nil
+}
+
+// Classify maps a market title (and the names of its outcomes, used for
+// lines) to a canonical market
+func (c *Classifier) Classify(title string, outcomes []string) Market {
+    title = strings.TrimSpace(title)
+    market := Market{Kind: Other, Map: mapNumber(title)}
+
+    for _, r := range c.rules {
+        m := r.re.FindStringSubmatch(title)
+        if m == nil || r.RequiresMap && market.Map == 0 {
+            continue
+        }
+
+        market.Kind = r.Kind
+
+        for i, name := range r.re.SubexpNames() {
+            if len(m[i]) == 0 {
+                continue
+            }
+
+            switch {
+            case strings.HasPrefix(name, "map"):
+                if n, err := strconv.Atoi(m[i]); err == nil {
+                    market.Map = n
+                }
+            case strings.HasPrefix(name, "line"):
+                market.Line = parseLine(m[i])
+            }
+        }
+
+        if market.Line == nil && r.LineFromOutcomes {
+            for _, o := range outcomes {
+                if market.Line = outcomeLine(o); market.Line != nil {
+                    break
+                }
+            }
+        }
+
+        break
+    }
+
+    return market
+}
+
+// Apply classifies the bet in place
+func (c *Classifier) Apply(bet *domain.Bet) {
+    var outcomes []string
+
+    for _, o := range bet.Opts {
+        outcomes = append(outcomes, o.Name)
+    }
+
+    m := c.Classify(bet.Type, outcomes)
+
+    bet.Kind = m.Kind
+    bet.Map = m.Map
+    bet.Line = m.Line
+}
+
+// mapNumber is the first whole number mentioned as a map in the title,
+// lines like "карт 2.5" are skipped
+func mapNumber(title string) int {
+    for _, m := range mapRe.FindAllStringSubmatch(title, -1) {
+        for _, g := range m[1:] {
+            if n, err := strconv.Atoi(g); err == nil {
+                return n
+            }
+        }
+    }
+
+    return 0
+}
+
+func outcomeLine(name string) *float64 {
+    m := lineRe.FindStringSubmatch(strings.TrimSpace(name))
+    if m == nil {
+        return nil
+    }
+
+    return parseLine(m[1])
+}
+
+func parseLine(s string) *float64 {
+    v, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+    if err != nil {
+        return nil
+    }
+
+    return &v
+}
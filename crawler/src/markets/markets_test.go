@@ -0,0 +1,121 @@
+package markets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func line(v float64) *float64 {
+    return &v
+}
+
+func TestClassify(t *testing.T) {
+    cl := Default()
+
+    cases := []struct {
+        title string
+        outcomes []string
+        want Market
+    }{
+        {"Первая кровь", nil, Market{Kind: FirstBlood}},
+        {"Карта 2. Первая кровь", nil, Market{Kind: FirstBlood, Map: 2}},
+        {"Map 1 - First Blood", nil, Market{Kind: FirstBlood, Map: 1}},
+        {"Первая башня", nil, Market{Kind: FirstTower}},
+        {"1-я карта: первая вышка", nil, Market{Kind: FirstTower, Map: 1}},
+        {"First Roshan", nil, Market{Kind: FirstRoshan}},
+        {"Карта 1. Тотал убийств", []string{"Больше (48.5)", "Меньше (48.5)"}, Market{Kind: MapTotalKills, Map: 1, Line: line(48.5)}},
+        {"Тотал убийств 52,5", []string{"Больше", "Меньше"}, Market{Kind: MapTotalKills, Line: line(52.5)}},
+        {"Map 3 Total Kills", nil, Market{Kind: MapTotalKills, Map: 3}},
+        {"Продолжительность карты 2", []string{"Больше 38.5", "Меньше 38.5"}, Market{Kind: MapDuration, Map: 2, Line: line(38.5)}},
+        {"Тотал карт", []string{"Больше (2.5)", "Меньше (2.5)"}, Market{Kind: TotalMaps, Line: line(2.5)}},
+        {"Тотал карт 2.5", []string{"Больше", "Меньше"}, Market{Kind: TotalMaps, Line: line(2.5)}},
+        {"Тотал карт (2,5)", nil, Market{Kind: TotalMaps, Line: line(2.5)}},
+        {"Total Maps", []string{"Over 2.5", "Under 2.5"}, Market{Kind: TotalMaps, Line: line(2.5)}},
+        {"Точный счёт", []string{"2:0", "2:1"}, Market{Kind: CorrectScore}},
+        {"Correct Score", nil, Market{Kind: CorrectScore}},
+        {"Карта 1. Фора", []string{"Team Spirit (-5.5)", "OG (+5.5)"}, Market{Kind: MapHandicap, Map: 1, Line: line(-5.5)}},
+        {"Фора", []string{"Team Spirit -1,5", "OG +1,5"}, Market{Kind: MatchHandicap, Line: line(-1.5)}},
+        {"Handicap", nil, Market{Kind: MatchHandicap}},
+        {"Победитель карты 3", nil, Market{Kind: MapWinner, Map: 3}},
+        {"2nd map winner", nil, Market{Kind: MapWinner, Map: 2}},
+        {"Карта 2", nil, Market{Kind: MapWinner, Map: 2}},
+        {"Победитель", nil, Market{Kind: MatchWinner}},
+        {"Исход матча", nil, Market{Kind: MatchWinner}},
+        {"Match Result", nil, Market{Kind: MatchWinner}},
+        {"1X2", nil, Market{Kind: MatchWinner}},
+        {"Чётный тотал", nil, Market{Kind: Other}},
+        {"", nil, Market{Kind: Other}},
+    }
+
+    for _, c := range cases {
+        t.Run(c.title, func(t *testing.T) {
+            got := cl.Classify(c.title, c.outcomes)
+
+            if got.Kind != c.want.Kind || got.Map != c.want.Map {
+                t.Errorf("got %s map %d, want %s map %d", got.Kind, got.Map, c.want.Kind, c.want.Map)
+            }
+
+            switch {
+            case got.Line == nil && c.want.Line == nil:
+            case got.Line == nil || c.want.Line == nil || *got.Line != *c.want.Line:
+                t.Errorf("got line %v, want %v", got.Line, c.want.Line)
+            }
+        })
+    }
+}
+
+func TestMapNumber(t *testing.T) {
+    cases := []struct {
+        title string
+        want int
+    }{
+        {"Карта 2", 2},
+        {"карту №3", 3},
+        {"map 1", 1},
+        {"3-я карта", 3},
+        {"2nd map", 2},
+        {"Тотал карт 2.5", 0},
+        {"Тотал 2,5 карт", 0},
+        {"Тотал карт 2.5, карта 1", 1},
+        {"Победитель", 0},
+    }
+
+    for _, c := range cases {
+        if got := mapNumber(c.title); got != c.want {
+            t.Errorf("mapNumber(%q) = %d, want %d", c.title, got, c.want)
+        }
+    }
+}
+
+func TestLoad(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "rules.yaml")
+
+    err := os.WriteFile(path, []byte("rules:\n  - kind: first_blood\n    title: '(?i)^fb$'\n"), 0644)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    c, err := Load(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if got := c.Classify("FB", nil); got.Kind != FirstBlood {
+        t.Errorf("extra rule not applied, got %s", got.Kind)
+    }
+
+    // the built in rules still apply after the extra ones
+    if got := c.Classify("Тотал карт 2.5", nil); got.Kind != TotalMaps {
+        t.Errorf("built in rule not applied, got %s", got.Kind)
+    }
+
+    err = os.WriteFile(path, []byte("rules:\n  - kind: first_blood\n    title: '(?P<'\n"), 0644)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := Load(path); err == nil {
+        t.Error("loaded a rule with a bad title pattern")
+    }
+}
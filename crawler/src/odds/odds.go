@@ -0,0 +1,128 @@
+package odds
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"mxshs/crawler/src/retry"
+)
+
+type Format string
+
+const (
+    Decimal Format = "decimal"
+    Fractional Format = "fractional"
+    American Format = "american"
+)
+
+// Price is a scraped odds value converted to decimal odds
+type Price struct {
+    Decimal float64
+    Format Format
+    // Suspended is set for outcomes that are shown but can not be bet on
+    // (locked buttons, dashes, empty coefficient cells)
+    Suspended bool
+    Raw string
+}
+
+var suspendedValues = map[string]bool{
+    "": true,
+    "-": true,
+    "—": true,
+    "–": true,
+    "\U0001F512": true,
+    "susp": true,
+    "suspended": true,
+    "locked": true,
+    "closed": true,
+    "n/a": true,
+    "закрыт": true,
+    "закрыто": true,
+    "приостановлено": true,
+}
+
+// MaxDecimal is the largest price that fits the numeric(10, 3) columns
+// prices are stored in
+const MaxDecimal = 9999999.999
+
+// the forms a price may take, anything else (exponents, hex, inf, NaN,
+// negative fractions) is rejected rather than stored
+var (
+    decimalRe = regexp.MustCompile(`^\d+(?:[.,]\d+)?$`)
+    fractionalRe = regexp.MustCompile(`^\d+/\d+$`)
+    americanRe = regexp.MustCompile(`^[+-]\d+$`)
+)
+
+// Parse converts a scraped odds value to a decimal price. Values that are
+// not a price, or one out of the storable range, fail with a retry.Parse
+// error
+func Parse(raw string) (Price, error) {
+    price := Price{Raw: raw}
+
+    value := strings.Join(strings.FieldsFunc(raw, unicode.IsSpace), "")
+    lower := strings.ToLower(value)
+
+    if suspendedValues[lower] {
+        price.Suspended = true
+        return price, nil
+    }
+
+    switch {
+    case lower == "evs" || lower == "evens":
+        price.Format = Fractional
+        price.Decimal = 2
+
+    case fractionalRe.MatchString(value):
+        num, den, _ := strings.Cut(value, "/")
+
+        n, err := strconv.ParseFloat(num, 64)
+        if err != nil {
+            return price, retry.Errorf(retry.Parse, "[ERROR] Failed to parse fractional odds (numerator): %s", raw)
+        }
+
+        d, err := strconv.ParseFloat(den, 64)
+        if err != nil || d == 0 {
+            return price, retry.Errorf(retry.Parse, "[ERROR] Failed to parse fractional odds (denominator): %s", raw)
+        }
+
+        price.Format = Fractional
+        price.Decimal = 1 + n / d
+
+    case americanRe.MatchString(value):
+        v, err := strconv.Atoi(value)
+        if err != nil || v > -100 && v < 100 {
+            return price, retry.Errorf(retry.Parse, "[ERROR] Failed to parse american odds: %s", raw)
+        }
+
+        price.Format = American
+        if v > 0 {
+            price.Decimal = 1 + float64(v) / 100
+        } else {
+            price.Decimal = 1 + 100 / float64(-v)
+        }
+
+    case decimalRe.MatchString(value):
+        v, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+        if err != nil {
+            return price, retry.Errorf(retry.Parse, "[ERROR] Failed to parse decimal odds: %s", raw)
+        }
+
+        if v < 1 {
+            return price, retry.Errorf(retry.Parse, "[ERROR] Decimal odds below 1: %s", raw)
+        }
+
+        price.Format = Decimal
+        price.Decimal = v
+
+    default:
+        return price, retry.Errorf(retry.Parse, "[ERROR] Not an odds value: %s", raw)
+    }
+
+    if price.Decimal > MaxDecimal {
+        return Price{Raw: raw}, retry.Errorf(retry.Parse, "[ERROR] Odds out of range: %s", raw)
+    }
+
+    return price, nil
+}
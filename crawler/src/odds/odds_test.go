@@ -0,0 +1,86 @@
+package odds
+
+import (
+	"testing"
+
+	"mxshs/crawler/src/retry"
+)
+
+func TestParse(t *testing.T) {
+    cases := []struct {
+        raw string
+        decimal float64
+        format Format
+        suspended bool
+    }{
+        {"1.85", 1.85, Decimal, false},
+        {" 2.10 ", 2.10, Decimal, false},
+        {"1,72", 1.72, Decimal, false},
+        {"1", 1, Decimal, false},
+        {"150", 150, Decimal, false},
+        {"5/2", 3.5, Fractional, false},
+        {"1/4", 1.25, Fractional, false},
+        {"EVS", 2, Fractional, false},
+        {"evens", 2, Fractional, false},
+        {"+150", 2.5, American, false},
+        {"-200", 1.5, American, false},
+        {"+100", 2, American, false},
+        {"", 0, "", true},
+        {"—", 0, "", true},
+        {"-", 0, "", true},
+        {"\U0001F512", 0, "", true},
+        {"Закрыто", 0, "", true},
+        {"suspended", 0, "", true},
+    }
+
+    for _, c := range cases {
+        t.Run(c.raw, func(t *testing.T) {
+            price, err := Parse(c.raw)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if price.Suspended != c.suspended || price.Format != c.format || price.Decimal != c.decimal {
+                t.Errorf("got %+v, want %v %s suspended=%v", price, c.decimal, c.format, c.suspended)
+            }
+        })
+    }
+}
+
+func TestParseRejects(t *testing.T) {
+    cases := []string{
+        "inf",
+        "+Inf",
+        "NaN",
+        "0x1p1",
+        "1e9",
+        "1.5e2",
+        "-5/2",
+        "5/-2",
+        "5/0",
+        "0.95",
+        "+50",
+        "-99",
+        "1.2.3",
+        "abc",
+        "99999999",
+        "+9999999999",
+    }
+
+    for _, raw := range cases {
+        t.Run(raw, func(t *testing.T) {
+            price, err := Parse(raw)
+            if err == nil {
+                t.Fatalf("got %+v, want an error", price)
+            }
+
+            if class := retry.ClassOf(err); class != retry.Parse {
+                t.Errorf("error class %s, want %s", class, retry.Parse)
+            }
+
+            if price.Decimal != 0 {
+                t.Errorf("price %v kept for a rejected value", price.Decimal)
+            }
+        })
+    }
+}
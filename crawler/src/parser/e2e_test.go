@@ -0,0 +1,312 @@
+//go:build e2e
+
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/fakesite"
+	"mxshs/crawler/src/retry"
+)
+
+// The end to end tests crawl fake copies of the sites with a local headless
+// Chrome, which has to be installed:
+//
+//     go test -tags e2e ./src/parser
+//
+// The pages are the golden fixtures of src/core/testdata wrapped into what
+// each parser waits for before reading the page
+
+// site is how a fake copy of a bookmaker serves its fixtures
+type site struct {
+    code string
+    // listing and match page, match urls are the ones linked from the listing
+    listing func(html string) fakesite.Page
+    match func(html string) fakesite.Page
+    matches []string
+    // wait is what the parser waits for on match pages
+    wait string
+}
+
+var sites = []site{
+    {
+        code: "leon",
+        listing: func(html string) fakesite.Page {
+            return fakesite.Page{HTML: `<div><div class="sport-event-region">` + html + `</div></div>`}
+        },
+        match: func(html string) fakesite.Page {
+            return fakesite.Page{HTML: `<div><div class="sport-event-details">` + html + `</div></div>`}
+        },
+        matches: []string{
+            "/bets/esports/dota2/1970324845210131-team-spirit-og",
+            "/bets/esports/dota2/1970324845210132-tundra-esports-gaimin-gladiators",
+        },
+        wait: `.sport-event-details-market-list_pY0E1`,
+    },
+    {
+        code: "ligastavok",
+        listing: func(html string) fakesite.Page {
+            return fakesite.Page{HTML: html}
+        },
+        match: func(html string) fakesite.Page {
+            return fakesite.Page{HTML: `<div><div id="content">` + html + `</div></div>`}
+        },
+        matches: []string{
+            "/Esports/Dota-2/The-International-2026/Team-Spirit-OG-id-29120341",
+            "/Esports/Dota-2/The-International-2026/Tundra-Esports-Gaimin-Gladiators-id-29120342",
+        },
+        wait: `#content`,
+    },
+    {
+        code: "d2lounge",
+        listing: func(html string) fakesite.Page {
+            return fakesite.Page{HTML: `<div><div class="match_page">` + html + `</div></div>`}
+        },
+        match: func(html string) fakesite.Page {
+            return fakesite.Page{HTML: `<div><div class="match_page">` + html + `</div></div>`}
+        },
+        matches: []string{"/match/98765", "/match/98766"},
+        wait: `.match_page`,
+    },
+    {
+        code: "ggbet",
+        listing: func(html string) fakesite.Page {
+            return fakesite.Page{HTML: `<div data-test="sport-event-list">` + html + `</div>`}
+        },
+        // markets show up once the All tab was clicked
+        match: func(html string) fakesite.Page {
+            return fakesite.Page{
+                HTML: `<div data-tab="All">All</div>`,
+                Click: `div[data-tab="All"]`,
+                Reveal: html,
+            }
+        },
+        matches: []string{
+            "/en/esports/match/team-spirit-vs-og-14-03",
+            "/en/esports/match/tundra-esports-vs-gaimin-gladiators-14-03",
+        },
+        wait: `div[data-tab="All"]`,
+    },
+}
+
+// serve starts a fake copy of s, the bookmaker points at it until the test
+// is done. page replaces the match pages when not nil
+func serve(t *testing.T, s site, page func(html string) []fakesite.Page) *fakesite.Server {
+    t.Helper()
+
+    srv := fakesite.Start()
+
+    // Override keeps only the fake's host, the saved bookmaker is defined
+    // again afterwards so its base url and hosts are back for later tests
+    b, ok := bookmaker.ByCode(s.code)
+    if !ok {
+        t.Fatalf("unknown bookmaker %q", s.code)
+    }
+
+    t.Cleanup(func() {
+        srv.Close()
+
+        err := bookmaker.Define(b)
+        if err != nil {
+            t.Error(err)
+        }
+    })
+
+    err := bookmaker.Override(s.code, srv.URL(s.code))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    srv.Handle(s.code, "/listing", s.listing(fixture(t, s.code, "listing")))
+
+    match := fixture(t, s.code, "match")
+    for _, path := range s.matches {
+        if page != nil {
+            srv.Handle(s.code, path, page(match)...)
+        } else {
+            srv.Handle(s.code, path, s.match(match))
+        }
+    }
+
+    return srv
+}
+
+func fixture(t *testing.T, code string, name string) string {
+    t.Helper()
+
+    html, err := os.ReadFile(filepath.Join("..", "core", "testdata", code, name + ".html"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return string(html)
+}
+
+func crawlOpts() Options {
+    return Options{
+        Workers: 2,
+        PageTimeout: 10 * time.Second,
+        Retry: retry.Policy{Attempts: 2, Backoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+    }
+}
+
+func run(t *testing.T, srv *fakesite.Server, code string, opts Options) (Result, *db.MemoryDB) {
+    t.Helper()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Minute)
+    defer cancel()
+
+    store := db.GetMemoryDB()
+
+    res, err := Parse(ctx, store, srv.URL(code) + "/listing", opts)
+    if err != nil {
+        t.Fatalf("crawling %s: %s", code, err.Error())
+    }
+
+    return res, store
+}
+
+func TestE2ECrawl(t *testing.T) {
+    for _, s := range sites {
+        t.Run(s.code, func(t *testing.T) {
+            srv := serve(t, s, nil)
+
+            res, store := run(t, srv, s.code, crawlOpts())
+
+            if res.Stats.Failed > 0 {
+                t.Errorf("%d pages failed: %v", res.Stats.Failed, res.Stats.Errors)
+            }
+
+            games := store.Games()
+            if len(games) == 0 {
+                t.Fatal("no game was stored")
+            }
+
+            for i, game := range games {
+                if len(game.TeamA) == 0 || len(game.TeamB) == 0 {
+                    t.Errorf("game %d has no teams: %+v", i + 1, game)
+                }
+
+                if len(store.Snapshots(i + 1)) == 0 {
+                    t.Errorf("game %d has no odds", i + 1)
+                }
+            }
+        })
+    }
+}
+
+// content rendered a while after the page loaded is still picked up
+func TestE2EDelayedRender(t *testing.T) {
+    for _, s := range sites {
+        t.Run(s.code, func(t *testing.T) {
+            srv := serve(t, s, func(html string) []fakesite.Page {
+                page := s.match(html)
+                page.Delay = 2 * time.Second
+
+                return []fakesite.Page{page}
+            })
+
+            res, store := run(t, srv, s.code, crawlOpts())
+
+            if res.Stats.Failed > 0 {
+                t.Errorf("%d pages failed: %v", res.Stats.Failed, res.Stats.Errors)
+            }
+
+            if len(store.Games()) == 0 {
+                t.Error("no game was stored")
+            }
+        })
+    }
+}
+
+// match pages missing the element the parser waits for fail as a changed
+// layout once the page timeout runs out
+func TestE2EMissingElement(t *testing.T) {
+    for _, s := range sites {
+        t.Run(s.code, func(t *testing.T) {
+            srv := serve(t, s, func(html string) []fakesite.Page {
+                page := s.match(html)
+                page.Omit = []string{s.wait}
+
+                return []fakesite.Page{page}
+            })
+
+            opts := crawlOpts()
+            opts.PageTimeout = 3 * time.Second
+
+            res, store := run(t, srv, s.code, opts)
+
+            if n := res.Stats.Errors[string(retry.SelectorNotFound)]; n != len(s.matches) {
+                t.Errorf("want %d %s errors, got %v", len(s.matches), retry.SelectorNotFound, res.Stats.Errors)
+            }
+
+            if len(store.Games()) > 0 {
+                t.Errorf("stored %d games from empty pages", len(store.Games()))
+            }
+        })
+    }
+}
+
+func TestE2EErrorResponses(t *testing.T) {
+    cases := []struct {
+        name string
+        page fakesite.Page
+        class retry.Class
+        // requests per match page, errors worth retrying are tried again
+        hits int
+    }{
+        {"forbidden", fakesite.Page{Status: 403}, retry.Blocked, 1},
+        {"bot protection", fakesite.Page{Header: map[string][]string{"cf-mitigated": {"challenge"}}}, retry.Blocked, 1},
+        {"rate limited", fakesite.Page{Status: 429}, retry.Network, 2},
+        {"geo blocked", fakesite.Page{Status: 451}, retry.GeoBlocked, 1},
+        {"unavailable", fakesite.Page{Status: 503}, retry.Network, 2},
+    }
+
+    s := sites[0]
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            srv := serve(t, s, func(html string) []fakesite.Page {
+                return []fakesite.Page{c.page}
+            })
+
+            res, _ := run(t, srv, s.code, crawlOpts())
+
+            // every failed attempt is counted
+            if n := res.Stats.Errors[string(c.class)]; n != len(s.matches) * c.hits {
+                t.Errorf("want %d %s errors, got %v", len(s.matches) * c.hits, c.class, res.Stats.Errors)
+            }
+
+            for _, path := range s.matches {
+                if n := srv.Hits(s.code, path); n != c.hits {
+                    t.Errorf("%s was requested %d times, want %d", path, n, c.hits)
+                }
+            }
+        })
+    }
+}
+
+// a page that fails once is crawled by the retry
+func TestE2ERetry(t *testing.T) {
+    s := sites[0]
+
+    srv := serve(t, s, func(html string) []fakesite.Page {
+        return []fakesite.Page{{Status: 503}, s.match(html)}
+    })
+
+    res, store := run(t, srv, s.code, crawlOpts())
+
+    if res.Stats.Failed > 0 {
+        t.Errorf("%d pages failed: %v", res.Stats.Failed, res.Stats.Errors)
+    }
+
+    if len(store.Games()) == 0 {
+        t.Error("no game was stored")
+    }
+}
@@ -1,56 +1,349 @@
 package parser
 
 import (
+    "context"
     "fmt"
+    neturl "net/url"
+    "slices"
     "sync"
+    "time"
 
+	"mxshs/crawler/src/archive"
+	"mxshs/crawler/src/browser"
 	"mxshs/crawler/src/core"
 	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/markets"
+	"mxshs/crawler/src/priority"
+	"mxshs/crawler/src/ratelimit"
+	"mxshs/crawler/src/retry"
+	"mxshs/crawler/src/teams"
 )
 
-func Parse(url string) error {
-    db, err := db.GetDB()
+// Options of a crawl, see config.Crawl for what they mean and their defaults
+type Options struct {
+    // Workers is the number of match pages loaded at once
+    Workers int
+    // RunTimeout bounds the whole crawl, 0 means no limit
+    RunTimeout time.Duration
+    // PageTimeout bounds loading a single page
+    PageTimeout time.Duration
+    Browsers browser.Options
+    // requests per second to each host (0 disables limiting), bursts and the
+    // random delay before every request
+    RateLimit float64
+    RateBurst int
+    RateJitter time.Duration
+    Retry retry.Policy
+    // MarketRules are extra market rule files tried before the built in ones
+    MarketRules []string
+    // Priority crawls only the matches that are due according to their start
+    // time, most urgent first. Every listed match is crawled when nil
+    Priority *priority.Policy
+    // Queue keeps the match pages of a crawl as jobs in the storage, which
+    // must implement db.Queue. Pages are crawled straight from the listing
+    // when nil
+    Queue *QueueOptions
+    // Archive keeps the HTML of every loaded page when set
+    Archive *archive.Archive
+}
+
+// Result of a crawl
+type Result struct {
+    RunID int64
+    Stats db.RunStats
+}
+
+// Parse crawls every match listed on the page at url with the parser
+// registered for its site and writes them to store. Cancelling ctx stops
+// loading new pages, pages already loaded are still stored and the run is
+// closed as cancelled
+func Parse(ctx context.Context, store db.Storage, url string, opts Options) (Result, error) {
+    var result Result
+
+    reg, err := core.Lookup(url)
     if err != nil {
-        return err
+        return result, err
+    }
+
+    if !reg.Can(core.MatchList) || !reg.Can(core.MatchOdds) {
+        return result, fmt.Errorf("[ERROR] %s parser can not crawl listing pages\n", reg.Bookmaker.Name)
+    }
+
+    var queue db.Queue
+    if opts.Queue != nil {
+        var ok bool
+        if queue, ok = store.(db.Queue); !ok {
+            return result, fmt.Errorf("[ERROR] Selected storage has no job queue")
+        }
     }
 
-    p := core.GetLeonParser(db)
+    if opts.RunTimeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, opts.RunTimeout)
+        defer cancel()
+    }
+
+    run_id, err := store.StartRun(ctx, reg.Bookmaker, url)
+    if err != nil {
+        return result, err
+    }
+
+    result.RunID = run_id
+
+    // the listing is not one of the pages of the run, only its failed
+    // attempts are counted with theirs
+    stats := &retry.Stats{}
+    listing := &retry.Stats{}
+
+    // the run is closed even after ctx was cancelled, that is what it records
+    finish := func(runErr error) (Result, error) {
+        ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10 * time.Second)
+        defer cancel()
+
+        pages, failed, errs := stats.Summary()
+        _, _, listingErrs := listing.Summary()
+        for class, n := range listingErrs {
+            errs[class] += n
+        }
+
+        result.Stats = db.RunStats{Pages: pages, Failed: failed, Errors: errs}
+        logs.Infof("Run %d finished: %s", run_id, stats)
 
-    urls, err := p.ParseMatchUrls(url)
+        err := store.FinishRun(ctx, run_id, result.Stats, runErr)
+        if runErr != nil {
+            return result, runErr
+        }
+
+        return result, err
+    }
+
+    sess, err := openSession(store, opts)
     if err != nil {
+        return finish(err)
+    }
+    // stops the Chrome processes, also when the run was cancelled
+    defer sess.pool.Close()
+
+    p := sess.parser(reg, run_id)
+    limiter := sess.limiter
+    policy := opts.Retry
+
+    var urls []string
+
+    err = policy.Do(ctx, listing, func(ctx context.Context) error {
+        err := limiter.Wait(ctx, url)
+        if err != nil {
+            return err
+        }
+
+        urls, err = p.ParseMatchUrls(ctx, url)
+
         return err
+    })
+    if err != nil {
+        return finish(err)
     }
 
+    if opts.Priority != nil {
+        urls, err = due(ctx, store, reg, *opts.Priority, urls)
+        if err != nil {
+            return finish(err)
+        }
+    } else {
+        // listings are crawled from the bottom up
+        slices.Reverse(urls)
+    }
 
-    i := len(urls) - 1
-   
-    for i >= 0 {
+    if queue != nil {
+        added, err := queue.Enqueue(ctx, run_id, reg.Bookmaker.Code, url, urls)
+        if err != nil {
+            return finish(err)
+        }
 
-        counter := 2
+        logs.Infof("Queued %d pages, %d were queued already", added, len(urls) - added)
 
-        var wg sync.WaitGroup
+        // pages left over by earlier runs of the source are crawled as well
+        sess.drain(ctx, queue, reg.Bookmaker.Code, stats, 0)
+    } else {
+        crawl(ctx, p, limiter, policy, stats, opts.Workers, url, urls)
+    }
 
-        for i >= 0 && counter >= 0 {
+    err = sess.saveTeams()
+    if err != nil {
+        return finish(err)
+    }
 
-            wg.Add(1)
-            url := urls[i]
+    return finish(ctx.Err())
+}
 
-            go func() {
-                defer wg.Done()
+// session is what the parsers of a crawl share
+type session struct {
+    store db.Storage
+    opts Options
+    registry *teams.Registry
+    classifier *markets.Classifier
+    pool *browser.Pool
+    limiter *ratelimit.Limiter
+}
 
-                err := p.ParseAll(url)
-                if err != nil {
-                    fmt.Println(err.Error())
-                }
-            } ()
+// openSession loads the teams and market rules, the browsers are started on
+// first use and stopped by closing the pool
+func openSession(store db.Storage, opts Options) (*session, error) {
+    known, err := store.LoadTeams()
+    if err != nil {
+        return nil, err
+    }
+
+    classifier, err := markets.Load(opts.MarketRules...)
+    if err != nil {
+        return nil, err
+    }
 
-            counter -= 1
-            i -= 1
+    return &session{
+        store: store,
+        opts: opts,
+        registry: teams.GetRegistry(known),
+        classifier: classifier,
+        pool: browser.GetPool(opts.Browsers),
+        limiter: ratelimit.GetLimiter(opts.RateLimit, opts.RateBurst, opts.RateJitter),
+    }, nil
+}
+
+// parser of reg storing games under run_id
+func (s *session) parser(reg core.Registration, run_id int64) core.BetParser {
+    p := reg.New(s.store)
+    p.SetRunID(run_id)
+    p.SetTeams(s.registry)
+    p.SetMarkets(s.classifier)
+    p.SetBrowsers(s.pool)
+    p.SetPageTimeout(s.opts.PageTimeout)
+    p.SetArchive(s.opts.Archive)
+
+    return p
+}
+
+// saveTeams queues the team names nothing matched for review
+func (s *session) saveTeams() error {
+    unknown := s.registry.Unknown()
+    if len(unknown) > 0 {
+        logs.Infof(
+            "%d team names did not match any known team, review them with `crawler teams pending`",
+            len(unknown),
+        )
+    }
+
+    return s.store.SaveUnknownTeams(unknown)
+}
+
+// due keeps the listed matches that are due for a crawl, most urgent first.
+// Stored pages are absolute, listings may link them relative to the site
+func due(ctx context.Context, store db.Storage, reg core.Registration, policy priority.Policy, urls []string) ([]string, error) {
+    base, err := neturl.Parse(reg.Bookmaker.BaseURL)
+    if err != nil {
+        return nil, err
+    }
+
+    listed := map[string]string{}
+    abs := make([]string, 0, len(urls))
+
+    for _, url := range urls {
+        full := url
+        if ref, err := base.Parse(url); err == nil {
+            full = ref.String()
         }
 
-        wg.Wait()
+        listed[full] = url
+        abs = append(abs, full)
+    }
+
+    states, err := store.MatchStates(ctx, reg.Bookmaker.Code, abs)
+    if err != nil {
+        return nil, err
+    }
+
+    plan := policy.Order(time.Now(), abs, states)
+
+    logs.Infof(
+        "%d matches listed: %d due, %d crawled recently, %d already started",
+        len(urls),
+        len(plan.Due),
+        plan.Waiting,
+        plan.Started,
+    )
+
+    res := make([]string, 0, len(plan.Due))
+    for _, url := range plan.Due {
+        res = append(res, listed[url])
     }
 
-    return nil
+    return res, nil
 }
 
+// crawl parses match pages in the given order with a fixed number of
+// workers. The queue holds no more than one page per worker, so pages are
+// only taken from the listing as fast as workers finish them. Every attempt
+// to load a page waits for the rate limit of its host, failed pages are
+// retried according to policy
+func crawl(
+    ctx context.Context,
+    p core.BetParser,
+    limiter *ratelimit.Limiter,
+    policy retry.Policy,
+    stats *retry.Stats,
+    workers int,
+    start string,
+    urls []string,
+) {
+    if workers <= 0 {
+        workers = 1
+    }
+
+    base, _ := neturl.Parse(start)
+
+    jobs := make(chan string, workers)
+
+    var wg sync.WaitGroup
+
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+
+        go func() {
+            defer wg.Done()
+
+            for url := range jobs {
+                // match urls may be relative to the listing page
+                host := url
+                if base != nil {
+                    if ref, err := base.Parse(url); err == nil {
+                        host = ref.String()
+                    }
+                }
+
+                err := policy.Do(ctx, stats, func(ctx context.Context) error {
+                    err := limiter.Wait(ctx, host)
+                    if err != nil {
+                        return err
+                    }
+
+                    return p.ParseAll(ctx, url)
+                })
+                if err != nil && ctx.Err() == nil {
+                    logs.Errorf("Giving up on %s (%s): %s", url, retry.ClassOf(err), err.Error())
+                }
+            }
+        } ()
+    }
+
+    // stop handing out pages on cancellation, workers finish the ones they
+    // already took
+    for i := 0; i < len(urls) && ctx.Err() == nil; i++ {
+        select {
+        case jobs <- urls[i]:
+        case <-ctx.Done():
+        }
+    }
+    close(jobs)
+
+    wg.Wait()
+}
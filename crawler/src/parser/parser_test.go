@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/retry"
+)
+
+func TestParseStats(t *testing.T) {
+    network := retry.Errorf(retry.Network, "[ERROR] connection reset")
+    parse := retry.Errorf(retry.Parse, "[ERROR] no teams")
+
+    cases := []struct {
+        name string
+        listingFails []error
+        fails []error
+        pages int
+        failed int
+        errors map[string]int
+    }{
+        {"match pages only", nil, nil, 2, 0, map[string]int{}},
+        {"listing retried", []error{network}, nil, 2, 0, map[string]int{"network": 1}},
+        {"match page failed", nil, []error{parse}, 2, 1, map[string]int{"parse": 1}},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            p := &fakeParser{fails: c.fails, listingFails: c.listingFails, urls: []string{"/match/1", "/match/2"}}
+            register(t, p)
+
+            opts := Options{Workers: 1, Retry: retry.Policy{Attempts: 2}}
+
+            res, err := Parse(context.Background(), db.GetMemoryDB(), queueSite, opts)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            st := res.Stats
+            if st.Pages != c.pages || st.Failed != c.failed {
+                t.Errorf("got %d pages, %d failed, want %d, %d", st.Pages, st.Failed, c.pages, c.failed)
+            }
+
+            if len(st.Errors) != len(c.errors) {
+                t.Errorf("got errors %v, want %v", st.Errors, c.errors)
+            }
+            for class, n := range c.errors {
+                if st.Errors[class] != n {
+                    t.Errorf("got errors %v, want %v", st.Errors, c.errors)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,171 @@
+package parser
+
+import (
+    "context"
+    "fmt"
+    neturl "net/url"
+    "os"
+    "sync"
+    "time"
+
+	"mxshs/crawler/src/core"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+	"mxshs/crawler/src/retry"
+)
+
+// QueueOptions of crawls that go through the job queue
+type QueueOptions struct {
+    // Lease is how long a claimed page is reserved for a worker. A page
+    // whose lease ran out (the crawler crashed or hung) is handed out again
+    Lease time.Duration
+    // MaxAttempts is how many times a page is claimed before it is moved to
+    // the dead letter state
+    MaxAttempts int
+}
+
+// Work crawls the queued pages of source (of every source when empty) until
+// the queue is empty. With a positive poll it keeps waiting for new pages
+// until ctx is done instead. Games are stored under the runs that queued
+// the pages, so pages left over by a crashed crawl are finished here
+func Work(ctx context.Context, store db.Storage, source string, opts Options, poll time.Duration) (db.RunStats, error) {
+    var result db.RunStats
+
+    queue, ok := store.(db.Queue)
+    if !ok {
+        return result, fmt.Errorf("[ERROR] Selected storage has no job queue")
+    }
+
+    if opts.Queue == nil {
+        return result, fmt.Errorf("[ERROR] No queue options given")
+    }
+
+    sess, err := openSession(store, opts)
+    if err != nil {
+        return result, err
+    }
+    defer sess.pool.Close()
+
+    stats := &retry.Stats{}
+    sess.drain(ctx, queue, source, stats, poll)
+
+    pages, failed, errs := stats.Summary()
+    result = db.RunStats{Pages: pages, Failed: failed, Errors: errs}
+    logs.Infof("Worked off the queue: %s", stats)
+
+    err = sess.saveTeams()
+    if err != nil {
+        return result, err
+    }
+
+    return result, ctx.Err()
+}
+
+// drain claims and crawls jobs of source with opts.Workers workers until no
+// job is left, or until ctx is done when poll is positive
+func (s *session) drain(ctx context.Context, queue db.Queue, source string, stats *retry.Stats, poll time.Duration) {
+    workers := max(s.opts.Workers, 1)
+
+    host, err := os.Hostname()
+    if err != nil {
+        host = "crawler"
+    }
+
+    var wg sync.WaitGroup
+
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+
+        worker := fmt.Sprintf("%s:%d/%d", host, os.Getpid(), w)
+
+        go func() {
+            defer wg.Done()
+
+            for ctx.Err() == nil {
+                job, err := queue.Claim(ctx, source, worker, s.opts.Queue.Lease, s.opts.Queue.MaxAttempts)
+                if err != nil {
+                    if ctx.Err() == nil {
+                        logs.Errorf("Could not claim a job: %s", err.Error())
+                    }
+                    return
+                }
+
+                if job == nil {
+                    if poll <= 0 {
+                        return
+                    }
+
+                    select {
+                    case <-time.After(poll):
+                    case <-ctx.Done():
+                    }
+                    continue
+                }
+
+                s.work(ctx, queue, job, stats)
+            }
+        } ()
+    }
+
+    wg.Wait()
+}
+
+// work crawls the page of a claimed job and records the outcome. Crawling
+// stops when the lease runs out, by then the job may belong to another
+// worker. Pages that failed for a passing reason or ran out of lease go back
+// to the queue until they were claimed MaxAttempts times
+func (s *session) work(ctx context.Context, queue db.Queue, job *db.Job, stats *retry.Stats) {
+    jobCtx, cancel := context.WithDeadline(ctx, job.LeaseUntil)
+    defer cancel()
+
+    err := s.crawlJob(jobCtx, job, stats)
+
+    // the outcome is stored even when the crawler is shutting down
+    saveCtx, saveCancel := context.WithTimeout(context.WithoutCancel(ctx), 10 * time.Second)
+    defer saveCancel()
+
+    switch {
+    case err == nil:
+        err = queue.Complete(saveCtx, job)
+    case ctx.Err() != nil:
+        err = queue.Release(saveCtx, job)
+    case (retry.Retryable(retry.ClassOf(err)) || jobCtx.Err() != nil) && job.Attempts < s.opts.Queue.MaxAttempts:
+        class := retry.ClassOf(err)
+        logs.Errorf("Crawl of %s failed (%s), attempt %d of %d: %s", job.URL, class, job.Attempts, s.opts.Queue.MaxAttempts, err.Error())
+        err = queue.Retry(saveCtx, job, string(class), err)
+    default:
+        class := retry.ClassOf(err)
+        logs.Errorf("Giving up on %s (%s): %s", job.URL, class, err.Error())
+        err = queue.Bury(saveCtx, job, string(class), err)
+    }
+
+    if err != nil {
+        logs.Errorf("Could not update job %d: %s", job.ID, err.Error())
+    }
+}
+
+func (s *session) crawlJob(ctx context.Context, job *db.Job, stats *retry.Stats) error {
+    reg, err := core.Lookup(job.StartURL)
+    if err != nil {
+        return err
+    }
+
+    p := s.parser(reg, job.RunID)
+
+    // match urls may be relative to the listing page
+    host := job.URL
+    if base, err := neturl.Parse(job.StartURL); err == nil {
+        if ref, err := base.Parse(job.URL); err == nil {
+            host = ref.String()
+        }
+    }
+
+    return s.opts.Retry.Do(ctx, stats, func(ctx context.Context) error {
+        err := s.limiter.Wait(ctx, host)
+        if err != nil {
+            return err
+        }
+
+        return p.ParseAll(ctx, job.URL)
+    })
+}
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/core"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/retry"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const queueSite = "https://queue.test/dota2"
+
+// fakeParser fails the crawls of a page with the errors in fails, in order,
+// and succeeds once they are used up. A nil error hangs until ctx is done.
+// The listing fails with listingFails the same way and then lists urls
+type fakeParser struct {
+    core.Parser
+    fails []error
+    listingFails []error
+    urls []string
+
+    mu sync.Mutex
+    calls int
+}
+
+func (p *fakeParser) ParseMatchUrls(ctx context.Context, url string) ([]string, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if len(p.listingFails) > 0 {
+        err := p.listingFails[0]
+        p.listingFails = p.listingFails[1:]
+        return nil, err
+    }
+
+    return p.urls, nil
+}
+
+func (p *fakeParser) ParseAll(ctx context.Context, url string) error {
+    p.mu.Lock()
+    call := p.calls
+    p.calls++
+    p.mu.Unlock()
+
+    if call >= len(p.fails) {
+        return nil
+    }
+
+    if p.fails[call] == nil {
+        <-ctx.Done()
+        return retry.Wrap(retry.Timeout, ctx.Err())
+    }
+
+    return p.fails[call]
+}
+
+func (p *fakeParser) ParseMatchData(s *goquery.Selection) (*domain.GameBets, error) {
+    return nil, nil
+}
+
+func (p *fakeParser) ParseMatchBets(game *domain.GameBets, s *goquery.Selection) error {
+    return nil
+}
+
+func (p *fakeParser) ExtractMatchUrls(html string) ([]string, error) {
+    return nil, nil
+}
+
+func (p *fakeParser) Extract(html string, url string) (*domain.GameBets, error) {
+    return nil, nil
+}
+
+// register makes p the parser of queueSite
+func register(t *testing.T, p *fakeParser) {
+    t.Helper()
+
+    b := bookmaker.Bookmaker{Code: "queuetest", Name: "queuetest", BaseURL: "https://queue.test", Hosts: []string{"queue.test"}}
+
+    err := bookmaker.Define(b)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    core.Register(core.Registration{
+        Bookmaker: b,
+        Hosts: b.Hosts,
+        Capabilities: []core.Capability{core.MatchList, core.MatchOdds},
+        New: func(store db.Storage) core.BetParser {
+            return p
+        },
+    })
+}
+
+func TestWorkOutcomes(t *testing.T) {
+    network := retry.Errorf(retry.Network, "[ERROR] connection reset")
+    parse := retry.Errorf(retry.Parse, "[ERROR] no teams")
+
+    cases := []struct {
+        name string
+        fails []error
+        state string
+        calls int
+    }{
+        {"done", nil, db.JobDone, 1},
+        {"retried after a network error", []error{network}, db.JobDone, 2},
+        {"retried after the lease ran out", []error{nil}, db.JobDone, 2},
+        {"buried on a parse error", []error{parse}, db.JobDead, 1},
+        {"buried after max attempts", []error{network, network, network, network}, db.JobDead, 3},
+        {"buried after max leases", []error{nil, nil, nil}, db.JobDead, 3},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            p := &fakeParser{fails: c.fails}
+            register(t, p)
+
+            store := db.GetMemoryDB()
+
+            _, err := store.Enqueue(context.Background(), 1, "queuetest", queueSite, []string{"/match/1"})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            opts := Options{
+                Workers: 1,
+                Retry: retry.Policy{Attempts: 1},
+                Queue: &QueueOptions{Lease: 50 * time.Millisecond, MaxAttempts: 3},
+            }
+
+            _, err = Work(context.Background(), store, "queuetest", opts, 0)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            counts, err := store.QueueCounts(context.Background())
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if len(counts) != 1 || counts[0].State != c.state {
+                t.Errorf("queue %+v, want the job %s", counts, c.state)
+            }
+
+            if p.calls != c.calls {
+                t.Errorf("crawled %d times, want %d", p.calls, c.calls)
+            }
+        })
+    }
+}
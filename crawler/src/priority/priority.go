@@ -0,0 +1,163 @@
+package priority
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"mxshs/crawler/src/db"
+)
+
+// Tier revisits matches starting within Within every Every
+type Tier struct {
+    Within time.Duration `yaml:"within"`
+    Every time.Duration `yaml:"every"`
+}
+
+// Policy decides which matches of a listing are due for a crawl from their
+// stored start time: the closer the kickoff, the more often a match is
+// crawled. Matches not stored yet are always due, so their start time
+// becomes known
+type Policy struct {
+    // Tiers ordered by Within, the first one a match falls into applies
+    Tiers []Tier `yaml:"tiers"`
+    // Every is the interval of matches further away than the last tier
+    Every time.Duration `yaml:"every"`
+    // LiveEvery is the interval of matches that already started, 0 skips
+    // them. They are crawled for LiveFor after the start at most
+    LiveEvery time.Duration `yaml:"live_every"`
+    LiveFor time.Duration `yaml:"live_for"`
+}
+
+func Default() Policy {
+    return Policy{
+        Tiers: []Tier{
+            {Within: time.Hour, Every: 2 * time.Minute},
+            {Within: 6 * time.Hour, Every: 15 * time.Minute},
+            {Within: 24 * time.Hour, Every: time.Hour},
+        },
+        Every: 6 * time.Hour,
+        LiveFor: 3 * time.Hour,
+    }
+}
+
+// Validate returns the first problem found, meant to be reported by config
+func (p Policy) Validate() error {
+    for i, t := range p.Tiers {
+        if t.Within <= 0 || t.Every <= 0 {
+            return fmt.Errorf("tier %d needs a positive within and every", i + 1)
+        }
+
+        if i > 0 && t.Within <= p.Tiers[i - 1].Within {
+            return fmt.Errorf("tiers must be ordered by within, tier %d is not", i + 1)
+        }
+    }
+
+    if p.Every <= 0 {
+        return fmt.Errorf("every must be positive")
+    }
+
+    if p.LiveEvery < 0 || p.LiveFor < 0 {
+        return fmt.Errorf("live_every and live_for must not be negative")
+    }
+
+    return nil
+}
+
+// Shortest is the smallest interval of the policy. The tiers only pick
+// which listed matches a crawl loads, so no match is crawled more often
+// than the crawls run
+func (p Policy) Shortest() time.Duration {
+    res := p.Every
+
+    for _, t := range p.Tiers {
+        res = min(res, t.Every)
+    }
+
+    if p.LiveEvery > 0 {
+        res = min(res, p.LiveEvery)
+    }
+
+    return res
+}
+
+// Interval returns how often a match starting at start is crawled at now,
+// false when it is not crawled at all
+func (p Policy) Interval(now time.Time, start time.Time) (time.Duration, bool) {
+    until := start.Sub(now)
+
+    if until < 0 {
+        if p.LiveEvery <= 0 || -until > p.LiveFor {
+            return 0, false
+        }
+
+        return p.LiveEvery, true
+    }
+
+    for _, t := range p.Tiers {
+        if until <= t.Within {
+            return t.Every, true
+        }
+    }
+
+    return p.Every, true
+}
+
+// Plan is the outcome of Order
+type Plan struct {
+    // Due match urls, most urgent first
+    Due []string
+    // Waiting were crawled recently enough
+    Waiting int
+    // Started are skipped because the match already started
+    Started int
+}
+
+// Order picks the urls that are due at now given what is stored about them
+// (by url). New matches come first, then the rest by kickoff
+func (p Policy) Order(now time.Time, urls []string, states map[string]db.MatchState) Plan {
+    var plan Plan
+
+    type due struct {
+        url string
+        known bool
+        start time.Time
+    }
+
+    var queue []due
+
+    for _, url := range urls {
+        state, known := states[url]
+        if !known {
+            queue = append(queue, due{url: url})
+            continue
+        }
+
+        every, ok := p.Interval(now, state.Date)
+        if !ok {
+            plan.Started++
+            continue
+        }
+
+        if !state.CrawledAt.IsZero() && now.Sub(state.CrawledAt) < every {
+            plan.Waiting++
+            continue
+        }
+
+        queue = append(queue, due{url: url, known: true, start: state.Date})
+    }
+
+    sort.SliceStable(queue, func(i, j int) bool {
+        if queue[i].known != queue[j].known {
+            return !queue[i].known
+        }
+
+        return queue[i].start.Before(queue[j].start)
+    })
+
+    for _, d := range queue {
+        plan.Due = append(plan.Due, d.url)
+    }
+
+    return plan
+}
@@ -0,0 +1,119 @@
+package priority
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"mxshs/crawler/src/db"
+)
+
+var now = time.Date(2026, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+func TestInterval(t *testing.T) {
+    p := Default()
+    p.LiveEvery = 5 * time.Minute
+
+    cases := []struct {
+        name string
+        start time.Time
+        every time.Duration
+        ok bool
+    }{
+        {"starting now", now, 2 * time.Minute, true},
+        {"within the hour", now.Add(59 * time.Minute), 2 * time.Minute, true},
+        {"on the tier edge", now.Add(time.Hour), 2 * time.Minute, true},
+        {"later today", now.Add(5 * time.Hour), 15 * time.Minute, true},
+        {"tomorrow", now.Add(20 * time.Hour), time.Hour, true},
+        {"next week", now.Add(7 * 24 * time.Hour), 6 * time.Hour, true},
+        {"live", now.Add(-time.Hour), 5 * time.Minute, true},
+        {"over", now.Add(-4 * time.Hour), 0, false},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            every, ok := p.Interval(now, c.start)
+            if every != c.every || ok != c.ok {
+                t.Errorf("Interval = %s %v, want %s %v", every, ok, c.every, c.ok)
+            }
+        })
+    }
+
+    // live matches are skipped unless LiveEvery is set
+    if _, ok := Default().Interval(now, now.Add(-time.Minute)); ok {
+        t.Error("started match crawled without live_every")
+    }
+}
+
+func TestOrder(t *testing.T) {
+    p := Default()
+
+    states := map[string]db.MatchState{
+        "/soon": {Date: now.Add(30 * time.Minute), CrawledAt: now.Add(-5 * time.Minute)},
+        "/soon-fresh": {Date: now.Add(20 * time.Minute), CrawledAt: now.Add(-time.Minute)},
+        "/tonight": {Date: now.Add(5 * time.Hour), CrawledAt: now.Add(-20 * time.Minute)},
+        "/next-week": {Date: now.Add(7 * 24 * time.Hour), CrawledAt: now.Add(-time.Hour)},
+        "/started": {Date: now.Add(-time.Hour), CrawledAt: now.Add(-time.Hour)},
+        "/never-crawled": {Date: now.Add(3 * 24 * time.Hour)},
+    }
+
+    urls := []string{"/next-week", "/tonight", "/started", "/new-b", "/soon", "/soon-fresh", "/never-crawled", "/new-a"}
+
+    plan := p.Order(now, urls, states)
+
+    want := []string{"/new-b", "/new-a", "/soon", "/tonight", "/never-crawled"}
+    if !slices.Equal(plan.Due, want) {
+        t.Errorf("due %v, want %v", plan.Due, want)
+    }
+
+    if plan.Waiting != 2 || plan.Started != 1 {
+        t.Errorf("%d waiting and %d started, want 2 and 1", plan.Waiting, plan.Started)
+    }
+}
+
+func TestValidate(t *testing.T) {
+    cases := []struct {
+        name string
+        change func(p *Policy)
+        ok bool
+    }{
+        {"default", func(p *Policy) {}, true},
+        {"no tiers", func(p *Policy) { p.Tiers = nil }, true},
+        {"unordered tiers", func(p *Policy) { p.Tiers[0], p.Tiers[1] = p.Tiers[1], p.Tiers[0] }, false},
+        {"zero tier interval", func(p *Policy) { p.Tiers[0].Every = 0 }, false},
+        {"zero every", func(p *Policy) { p.Every = 0 }, false},
+        {"negative live", func(p *Policy) { p.LiveEvery = -time.Minute }, false},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            p := Default()
+            c.change(&p)
+
+            if err := p.Validate(); (err == nil) != c.ok {
+                t.Errorf("Validate = %v, want ok %v", err, c.ok)
+            }
+        })
+    }
+}
+
+func TestShortest(t *testing.T) {
+    live := Default()
+    live.LiveEvery = time.Minute
+
+    cases := []struct {
+        name string
+        p Policy
+        want time.Duration
+    }{
+        {"default", Default(), 2 * time.Minute},
+        {"live", live, time.Minute},
+        {"no tiers", Policy{Every: time.Hour}, time.Hour},
+    }
+
+    for _, c := range cases {
+        if got := c.p.Shortest(); got != c.want {
+            t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+        }
+    }
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket per host: each host gets Burst requests at once
+// and then Rate requests per second, every request is additionally delayed
+// by a random duration up to Jitter so they do not arrive in lockstep
+type Limiter struct {
+    Rate float64
+    Burst int
+    Jitter time.Duration
+
+    mu sync.Mutex
+    buckets map[string]*bucket
+}
+
+type bucket struct {
+    tokens float64
+    last time.Time
+}
+
+// GetLimiter returns a limiter, a zero or negative rate disables limiting
+func GetLimiter(rate float64, burst int, jitter time.Duration) *Limiter {
+    if burst <= 0 {
+        burst = 1
+    }
+
+    return &Limiter{
+        Rate: rate,
+        Burst: burst,
+        Jitter: jitter,
+        buckets: map[string]*bucket{},
+    }
+}
+
+// Wait blocks until a request to host may be sent or ctx is done
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+    delay := l.reserve(Host(host))
+
+    if l.Jitter > 0 {
+        delay += time.Duration(rand.Int63n(int64(l.Jitter)))
+    }
+
+    if delay <= 0 {
+        return ctx.Err()
+    }
+
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+
+    select {
+    case <-timer.C:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// reserve takes a token from the bucket of host and returns how long to wait
+// for it. Tokens may go negative, so concurrent callers queue up one after
+// another instead of all waking up at once
+func (l *Limiter) reserve(host string) time.Duration {
+    if l.Rate <= 0 {
+        return 0
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+
+    b, ok := l.buckets[host]
+    if !ok {
+        b = &bucket{tokens: float64(l.Burst), last: now}
+        l.buckets[host] = b
+    }
+
+    b.tokens += now.Sub(b.last).Seconds() * l.Rate
+    if b.tokens > float64(l.Burst) {
+        b.tokens = float64(l.Burst)
+    }
+    b.last = now
+
+    b.tokens--
+    if b.tokens >= 0 {
+        return 0
+    }
+
+    return time.Duration(-b.tokens / l.Rate * float64(time.Second))
+}
+
+// Host of an URL without the port and the "www." prefix, so that all pages
+// of a site share a bucket. Values that are not URLs are returned as is
+func Host(raw string) string {
+    u, err := url.Parse(raw)
+    if err != nil || len(u.Host) == 0 {
+        return strings.ToLower(raw)
+    }
+
+    return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
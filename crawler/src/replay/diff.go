@@ -0,0 +1,130 @@
+package replay
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/domain"
+)
+
+// Change ops
+const (
+    // only the replay has it
+    Added = "+"
+    // only the storage has it
+    Removed = "-"
+    // both have it with different values
+    Changed = "~"
+)
+
+// Change is one difference between a stored page and its replay
+type Change struct {
+    Op string
+    // Field is a game field (team_a, team_b, date, tournament), a market
+    // ("<market> kind") or an outcome ("<market> / <outcome>")
+    Field string
+    Stored string
+    Replayed string
+}
+
+func (c Change) String() string {
+    switch c.Op {
+    case Added:
+        return fmt.Sprintf("+ %s: %s", c.Field, c.Replayed)
+    case Removed:
+        return fmt.Sprintf("- %s: %s", c.Field, c.Stored)
+    default:
+        return fmt.Sprintf("~ %s: %s -> %s", c.Field, c.Stored, c.Replayed)
+    }
+}
+
+// Diff compares a stored page with the game replayed from the same page,
+// ordered by game fields first, then markets and outcomes by name
+func Diff(stored *db.StoredPage, game *domain.GameBets) []Change {
+    var res []Change
+
+    field := func(name string, before string, after string) {
+        if before != after {
+            res = append(res, Change{Op: Changed, Field: name, Stored: before, Replayed: after})
+        }
+    }
+
+    field("team_a", stored.Game.TeamA, game.TeamA)
+    field("team_b", stored.Game.TeamB, game.TeamB)
+    field("tournament", stored.Game.Tournament, game.Tournament)
+    field("date", date(stored.Game.Date), date(game.Date))
+
+    before := map[string]string{}
+    beforeKind := map[string]string{}
+
+    for _, s := range stored.Snapshots {
+        before[s.Market + " / " + s.Outcome] = s.Value
+        beforeKind[s.Market] = s.Kind
+    }
+
+    after := map[string]string{}
+    afterKind := map[string]string{}
+
+    for _, bet := range game.Bets {
+        afterKind[bet.Type] = bet.Kind
+
+        for _, opt := range bet.Opts {
+            after[bet.Type + " / " + opt.Name] = opt.Value
+        }
+    }
+
+    // markets only on one side show up through their outcomes
+    var kinds []Change
+    for market, b := range beforeKind {
+        if a, ok := afterKind[market]; ok && a != b {
+            kinds = append(kinds, Change{Op: Changed, Field: market + " kind", Stored: b, Replayed: a})
+        }
+    }
+
+    res = append(res, sorted(kinds)...)
+    res = append(res, compare(before, after)...)
+
+    return res
+}
+
+// compare reports the keys of two maps that differ, sorted by key
+func compare(before map[string]string, after map[string]string) []Change {
+    var res []Change
+
+    for k, b := range before {
+        a, ok := after[k]
+
+        switch {
+        case !ok:
+            res = append(res, Change{Op: Removed, Field: k, Stored: b})
+        case a != b:
+            res = append(res, Change{Op: Changed, Field: k, Stored: b, Replayed: a})
+        }
+    }
+
+    for k, a := range after {
+        if _, ok := before[k]; !ok {
+            res = append(res, Change{Op: Added, Field: k, Replayed: a})
+        }
+    }
+
+    return sorted(res)
+}
+
+func sorted(res []Change) []Change {
+    sort.Slice(res, func(i, j int) bool {
+        return res[i].Field < res[j].Field
+    })
+
+    return res
+}
+
+func date(t time.Time) string {
+    if t.IsZero() {
+        return ""
+    }
+
+    return t.UTC().Format(time.RFC3339)
+}
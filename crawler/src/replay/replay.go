@@ -0,0 +1,159 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mxshs/crawler/src/archive"
+	"mxshs/crawler/src/core"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/domain"
+	"mxshs/crawler/src/markets"
+	"mxshs/crawler/src/teams"
+)
+
+// Options of a replay
+type Options struct {
+    Teams *teams.Registry
+    Markets *markets.Classifier
+    // Stored is what replayed pages are compared with, nothing is compared
+    // when nil
+    Stored db.Reader
+    // Sink receives the replayed games, nothing is written when nil
+    Sink db.Storage
+}
+
+// Result of replaying one archived page
+type Result struct {
+    Entry archive.Entry
+    Game *domain.GameBets
+    // Err is why the page could not be parsed or written
+    Err error
+    // Stored is false when the page was never stored, there is no diff then
+    Stored bool
+    Diff []Change
+}
+
+// Summary counts the results of a replay
+type Summary struct {
+    Pages int
+    Changed int
+    Failed int
+    NotStored int
+}
+
+func (s Summary) String() string {
+    return fmt.Sprintf(
+        "%d pages, %d changed, %d failed, %d not stored before",
+        s.Pages,
+        s.Changed,
+        s.Failed,
+        s.NotStored,
+    )
+}
+
+// Run feeds the archived match pages matching f, oldest first, through the
+// extraction of the parser registered for their url, no browser involved.
+// Odds get the time the page was captured, so written games line up with
+// the history of the sink. Every result is passed to fn as it is ready
+func Run(ctx context.Context, a *archive.Archive, f archive.Filter, opts Options, fn func(Result) error) (Summary, error) {
+    var sum Summary
+
+    f.Kind = archive.Match
+
+    entries, err := a.Entries(f)
+    if err != nil {
+        return sum, err
+    }
+
+    for _, e := range entries {
+        if err := ctx.Err(); err != nil {
+            return sum, err
+        }
+
+        res := page(ctx, a, e, opts)
+
+        sum.Pages++
+        switch {
+        case res.Err != nil:
+            sum.Failed++
+        case opts.Stored != nil && !res.Stored:
+            sum.NotStored++
+        case len(res.Diff) > 0:
+            sum.Changed++
+        }
+
+        err := fn(res)
+        if err != nil {
+            return sum, err
+        }
+    }
+
+    return sum, nil
+}
+
+func page(ctx context.Context, a *archive.Archive, e archive.Entry, opts Options) Result {
+    res := Result{Entry: e}
+
+    html, err := a.Read(e.Hash)
+    if err != nil {
+        res.Err = err
+        return res
+    }
+
+    reg, err := core.Lookup(e.URL)
+    if err != nil {
+        res.Err = err
+        return res
+    }
+
+    p := reg.New(opts.Sink)
+    p.SetTeams(opts.Teams)
+    p.SetMarkets(opts.Markets)
+    p.SetClock(func() time.Time {
+        return e.CapturedAt
+    })
+
+    res.Game, err = p.Extract(html, e.URL)
+    if err != nil {
+        res.Err = err
+        return res
+    }
+
+    // the run the page was captured in may not exist in the sink
+    res.Game.RunID = 0
+
+    if opts.Stored != nil {
+        stored, err := opts.Stored.StoredPage(ctx, e.Source, e.URL, e.RunID)
+        if err != nil {
+            res.Err = err
+            return res
+        }
+
+        if stored != nil {
+            res.Stored = true
+            res.Diff = Diff(stored, res.Game)
+        }
+    }
+
+    if opts.Sink != nil {
+        res.Err = save(ctx, opts.Sink, res.Game)
+    }
+
+    return res
+}
+
+// save writes a replayed game, through ReplayGameBets when the sink has it so
+// stored games keep the run and crawl time of their last crawl
+func save(ctx context.Context, sink db.Storage, game *domain.GameBets) error {
+    var err error
+
+    if r, ok := sink.(db.Replayer); ok {
+        _, err = r.ReplayGameBets(ctx, game)
+    } else {
+        _, err = sink.SaveGameBets(ctx, game)
+    }
+
+    return err
+}
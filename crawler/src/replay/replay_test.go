@@ -0,0 +1,126 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"mxshs/crawler/src/archive"
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/core"
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/domain"
+)
+
+const matchURL = "https://leon.ru/bets/esports/dota2/1970324845210131-team-spirit-og"
+
+var firstCrawl = time.Date(2026, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+// crawl stores the page as a crawl of run_id at the given time would, with
+// change applied to the parsed game
+func crawl(t *testing.T, store *db.MemoryDB, html string, run_id int64, at time.Time, change func(*domain.GameBets)) {
+    t.Helper()
+
+    reg, err := core.Lookup(matchURL)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    p := reg.New(store)
+    p.SetClock(func() time.Time {
+        return at
+    })
+
+    game, err := p.Extract(html, matchURL)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    game.RunID = run_id
+    change(game)
+
+    _, err = store.SaveGameBets(context.Background(), game)
+    if err != nil {
+        t.Fatal(err)
+    }
+}
+
+// replaying a page over the games crawled around it adds only what was new
+// at the time it was captured and leaves the games as the crawls stored them
+func TestReplayOverStored(t *testing.T) {
+    ctx := context.Background()
+
+    raw, err := os.ReadFile("../core/testdata/leon/match.html")
+    if err != nil {
+        t.Fatal(err)
+    }
+    html := string(raw)
+
+    store := db.GetMemoryDB()
+
+    run_id, err := store.StartRun(ctx, bookmaker.Bookmaker{Code: "leon"}, "https://leon.ru/bets/esports/dota2")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    crawl(t, store, html, run_id, firstCrawl, func(*domain.GameBets) {})
+
+    // the price moved by the next crawl
+    crawl(t, store, html, run_id, firstCrawl.Add(2 * time.Hour), func(g *domain.GameBets) {
+        g.Bets[0].Opts[0].Value = "9.99"
+        g.Bets[0].Opts[0].Price = 9.99
+    })
+
+    games := store.Games()
+    snapshots := len(store.Snapshots(1))
+
+    states, err := store.MatchStates(ctx, "leon", []string{matchURL})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    a, err := archive.GetArchive(t.TempDir(), archive.Options{})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // captured between the crawls, with the prices of the first one
+    _, err = a.Put(archive.Entry{Kind: archive.Match, Source: "leon", URL: matchURL, RunID: run_id, CapturedAt: firstCrawl.Add(time.Hour)}, html)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    sum, err := Run(ctx, a, archive.Filter{}, Options{Stored: store, Sink: store}, func(res Result) error {
+        if res.Err != nil {
+            t.Errorf("replay of %s failed: %s", res.Entry.URL, res.Err.Error())
+        }
+
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if sum.Pages != 1 || sum.Failed != 0 {
+        t.Errorf("replayed %s, want one page", sum)
+    }
+
+    if n := len(store.Snapshots(1)); n != snapshots {
+        t.Errorf("%d snapshots after the replay, want %d", n, snapshots)
+    }
+
+    after := store.Games()
+    if len(after) != 1 || after[0].RunID != games[0].RunID {
+        t.Errorf("games %+v after the replay, want one of run %d", after, run_id)
+    }
+
+    replayed, err := store.MatchStates(ctx, "leon", []string{matchURL})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !replayed[matchURL].CrawledAt.Equal(states[matchURL].CrawledAt) {
+        t.Errorf("crawled at %s after the replay, want %s", replayed[matchURL].CrawledAt, states[matchURL].CrawledAt)
+    }
+}
@@ -0,0 +1,122 @@
+package resolve
+
+import (
+	"strings"
+	"time"
+
+	"mxshs/crawler/src/textsim"
+)
+
+// Fixture is a match as listed by one bookmaker or an already known event
+type Fixture struct {
+    ID int64
+    TeamA string
+    TeamB string
+    StartsAt time.Time
+    Tournament string
+}
+
+// Resolver decides whether fixtures from different bookmakers are the same
+// match. Teams are compared in both orders since sites disagree on home/away,
+// start times only have to be within Window of each other
+type Resolver struct {
+    Window time.Duration
+    // MinTeams is the minimal similarity of both team names, MinScore the
+    // minimal overall score for two fixtures to be considered the same
+    MinTeams float64
+    MinScore float64
+}
+
+func GetResolver() *Resolver {
+    return &Resolver{
+        Window: 3 * time.Hour,
+        MinTeams: 0.8,
+        MinScore: 0.75,
+    }
+}
+
+// words that sites add to or drop from team names at will
+var noise = map[string]bool{
+    "team": true,
+    "esports": true,
+    "esport": true,
+    "gaming": true,
+    "club": true,
+    "gg": true,
+}
+
+// NormalizeTeam brings a team name to the form it is compared in
+func NormalizeTeam(name string) string {
+    var res []string
+
+    for _, t := range textsim.Tokens(name) {
+        if !noise[t] {
+            res = append(res, t)
+        }
+    }
+
+    if len(res) == 0 {
+        return textsim.Normalize(name)
+    }
+
+    return strings.Join(res, " ")
+}
+
+func teamSimilarity(a, b string) float64 {
+    a, b = NormalizeTeam(a), NormalizeTeam(b)
+    if len(a) == 0 || len(b) == 0 {
+        return 0
+    }
+
+    return textsim.Similarity(a, b)
+}
+
+// Teams returns the similarity of the worse matching team pair, taking the
+// better of the straight and swapped order. One team alone matching does not
+// make the same fixture, so the pairs are not averaged
+func (r *Resolver) Teams(a, b Fixture) float64 {
+    straight := min(teamSimilarity(a.TeamA, b.TeamA), teamSimilarity(a.TeamB, b.TeamB))
+    swapped := min(teamSimilarity(a.TeamA, b.TeamB), teamSimilarity(a.TeamB, b.TeamA))
+
+    return max(straight, swapped)
+}
+
+// Score is 0 for fixtures that can not be the same match, otherwise a
+// weighted sum of team, start time and tournament similarity
+func (r *Resolver) Score(a, b Fixture) float64 {
+    dt := a.StartsAt.Sub(b.StartsAt).Abs()
+    if dt > r.Window {
+        return 0
+    }
+
+    teams := r.Teams(a, b)
+    if teams < r.MinTeams {
+        return 0
+    }
+
+    timing := 1 - float64(dt) / float64(r.Window)
+
+    // not every site shows the tournament, missing one is neutral
+    tournament := 0.5
+    if len(strings.TrimSpace(a.Tournament)) > 0 && len(strings.TrimSpace(b.Tournament)) > 0 {
+        tournament = textsim.Jaccard(a.Tournament, b.Tournament)
+    }
+
+    return 0.6 * teams + 0.25 * timing + 0.15 * tournament
+}
+
+// Match returns the best scoring candidate if it passes MinScore
+func (r *Resolver) Match(f Fixture, candidates []Fixture) (Fixture, bool) {
+    var best Fixture
+    bestScore := 0.0
+
+    for _, c := range candidates {
+        score := r.Score(f, c)
+        if score > bestScore {
+            best = c
+            bestScore = score
+        }
+    }
+
+    return best, bestScore >= r.MinScore
+}
@@ -0,0 +1,120 @@
+package resolve
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, time.March, 14, 18, 30, 0, 0, time.UTC)
+
+func fixture(a string, b string, startsAt time.Time, tournament string) Fixture {
+    return Fixture{TeamA: a, TeamB: b, StartsAt: startsAt, Tournament: tournament}
+}
+
+func TestNormalizeTeam(t *testing.T) {
+    cases := []struct {
+        name string
+        want string
+    }{
+        {"Team Spirit", "spirit"},
+        {"OG Esports", "og"},
+        {"Gaimin Gladiators", "gaimin gladiators"},
+        {"Team", "team"},
+        {"  Tundra-Esports ", "tundra"},
+    }
+
+    for _, c := range cases {
+        if got := NormalizeTeam(c.name); got != c.want {
+            t.Errorf("NormalizeTeam(%q) = %q, want %q", c.name, got, c.want)
+        }
+    }
+}
+
+func TestTeams(t *testing.T) {
+    r := GetResolver()
+
+    cases := []struct {
+        name string
+        a Fixture
+        b Fixture
+        same bool
+    }{
+        {"equal", fixture("Team Spirit", "OG", start, ""), fixture("Team Spirit", "OG", start, ""), true},
+        {"swapped", fixture("Team Spirit", "OG", start, ""), fixture("OG", "Team Spirit", start, ""), true},
+        {"noise words", fixture("Team Spirit", "OG Esports", start, ""), fixture("Spirit", "OG", start, ""), true},
+        {"typo", fixture("Gaimin Gladiators", "Tundra", start, ""), fixture("Gaimin Gladiator", "Tundra", start, ""), true},
+        {"one team only", fixture("Team Spirit", "OG", start, ""), fixture("Team Spirit", "Tundra", start, ""), false},
+        // averaged with the exact pair this used to pass
+        {"academy team", fixture("Team Spirit", "Tundra", start, ""), fixture("Team Spirit", "Tundra B", start, ""), false},
+        {"one team only swapped", fixture("Team Spirit", "OG", start, ""), fixture("Tundra", "Team Spirit", start, ""), false},
+        {"different", fixture("Team Spirit", "OG", start, ""), fixture("Tundra", "Gaimin Gladiators", start, ""), false},
+        {"empty name", fixture("", "OG", start, ""), fixture("", "OG", start, ""), false},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            got := r.Teams(c.a, c.b)
+
+            if same := got >= r.MinTeams; same != c.same {
+                t.Errorf("Teams = %.2f, same fixture %v, want %v", got, same, c.same)
+            }
+        })
+    }
+}
+
+func TestScore(t *testing.T) {
+    r := GetResolver()
+
+    a := fixture("Team Spirit", "OG", start, "The International 2026")
+
+    cases := []struct {
+        name string
+        b Fixture
+        same bool
+    }{
+        {"same", fixture("Spirit", "OG", start, "The International 2026"), true},
+        {"start a bit off", fixture("Spirit", "OG", start.Add(30 * time.Minute), "The International 2026"), true},
+        {"no tournament", fixture("Spirit", "OG", start, ""), true},
+        {"outside the window", fixture("Team Spirit", "OG", start.Add(4 * time.Hour), "The International 2026"), false},
+        {"rematch next day", fixture("Team Spirit", "OG", start.Add(24 * time.Hour), "The International 2026"), false},
+        {"one team only", fixture("Team Spirit", "Tundra", start, "The International 2026"), false},
+        {"academy team", fixture("Team Spirit", "OG B", start, "The International 2026"), false},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            score := r.Score(a, c.b)
+
+            if same := score >= r.MinScore; same != c.same {
+                t.Errorf("Score = %.2f, same fixture %v, want %v", score, same, c.same)
+            }
+        })
+    }
+}
+
+func TestMatch(t *testing.T) {
+    r := GetResolver()
+
+    f := fixture("Team Spirit", "OG", start, "The International 2026")
+
+    candidates := []Fixture{
+        {ID: 1, TeamA: "Tundra", TeamB: "OG", StartsAt: start},
+        {ID: 2, TeamA: "OG", TeamB: "Spirit", StartsAt: start.Add(2 * time.Hour)},
+        {ID: 3, TeamA: "Spirit", TeamB: "OG", StartsAt: start.Add(10 * time.Minute), Tournament: "The International 2026"},
+    }
+
+    best, ok := r.Match(f, candidates)
+    if !ok || best.ID != 3 {
+        t.Errorf("Match = %d (ok %v), want 3", best.ID, ok)
+    }
+
+    _, ok = r.Match(f, candidates[:1])
+    if ok {
+        t.Error("matched a fixture sharing a single team")
+    }
+
+    _, ok = r.Match(f, nil)
+    if ok {
+        t.Error("matched without candidates")
+    }
+}
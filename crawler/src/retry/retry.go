@@ -0,0 +1,236 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mxshs/crawler/src/logs"
+)
+
+// Class tells what went wrong while crawling a page and whether trying
+// again may help
+type Class string
+
+const (
+    // the page did not load in time
+    Timeout Class = "timeout"
+    // connection errors, 5xx responses and rate limiting (429)
+    Network Class = "network"
+    // the page loaded but an element the parser waits for never appeared,
+    // usually the layout changed
+    SelectorNotFound Class = "selector_not_found"
+    // bot protection or a captcha
+    Blocked Class = "blocked"
+    // the site is not available from our location
+    GeoBlocked Class = "geo_blocked"
+    // the page was loaded but its content could not be parsed
+    Parse Class = "parse"
+    // writing the parsed page failed
+    Storage Class = "storage"
+    Unknown Class = "unknown"
+)
+
+// Error is an error with its class attached
+type Error struct {
+    Class Class
+    Err error
+}
+
+func (e *Error) Error() string {
+    return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+    return e.Err
+}
+
+// Wrap attaches class to err, errors that already have a class keep it
+func Wrap(class Class, err error) error {
+    if err == nil {
+        return nil
+    }
+
+    var classified *Error
+    if errors.As(err, &classified) {
+        return err
+    }
+
+    return &Error{Class: class, Err: err}
+}
+
+// Errorf formats an error of the given class
+func Errorf(class Class, format string, args ...any) error {
+    return &Error{Class: class, Err: fmt.Errorf(format, args...)}
+}
+
+// ClassOf returns the class attached to err, deadline errors are timeouts
+// and anything else unclassified is Unknown
+func ClassOf(err error) Class {
+    var classified *Error
+
+    switch {
+    case err == nil:
+        return ""
+    case errors.As(err, &classified):
+        return classified.Class
+    case errors.Is(err, context.DeadlineExceeded):
+        return Timeout
+    case strings.Contains(err.Error(), "net::ERR_"):
+        // chromedp reports failed navigations with the Chrome error code
+        return Network
+    default:
+        return Unknown
+    }
+}
+
+// Retryable classes are transient, the rest fail the same way every time
+func Retryable(class Class) bool {
+    switch class {
+    case Timeout, Network, Storage:
+        return true
+    default:
+        return false
+    }
+}
+
+// Policy retries retryable errors with exponential backoff: the n-th retry
+// waits a random duration up to Backoff * 2^(n-1), capped at MaxBackoff
+type Policy struct {
+    Attempts int
+    Backoff time.Duration
+    MaxBackoff time.Duration
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable,
+// runs out of attempts or ctx is done. Every failed attempt is recorded in
+// stats when it is not nil
+func (p Policy) Do(ctx context.Context, stats *Stats, fn func(ctx context.Context) error) error {
+    attempts := max(p.Attempts, 1)
+
+    var err error
+
+    for attempt := 1; attempt <= attempts; attempt++ {
+        err = fn(ctx)
+        if err == nil || ctx.Err() != nil {
+            stats.done(err)
+            return err
+        }
+
+        class := ClassOf(err)
+        stats.fail(class)
+
+        if !Retryable(class) || attempt == attempts {
+            break
+        }
+
+        logs.Infof("Retrying after %s error (attempt %d of %d): %s", class, attempt, attempts, err.Error())
+
+        timer := time.NewTimer(p.delay(attempt))
+
+        select {
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            stats.done(ctx.Err())
+            return ctx.Err()
+        }
+    }
+
+    stats.done(err)
+
+    return err
+}
+
+func (p Policy) delay(attempt int) time.Duration {
+    if p.Backoff <= 0 {
+        return 0
+    }
+
+    d := p.Backoff << (attempt - 1)
+    if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+        d = p.MaxBackoff
+    }
+
+    // full jitter, so pages that failed together do not retry together
+    return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// Stats counts the outcome of every call made through a policy and the
+// failed attempts by class. A nil *Stats records nothing
+type Stats struct {
+    mu sync.Mutex
+    calls int
+    failed int
+    errors map[Class]int
+}
+
+func (s *Stats) fail(class Class) {
+    if s == nil {
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.errors == nil {
+        s.errors = map[Class]int{}
+    }
+    s.errors[class]++
+}
+
+func (s *Stats) done(err error) {
+    if s == nil {
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.calls++
+    if err != nil {
+        s.failed++
+    }
+}
+
+// Calls made, calls that failed after all attempts and failed attempts by
+// class (including those that succeeded on a retry)
+func (s *Stats) Summary() (calls int, failed int, byClass map[string]int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    byClass = map[string]int{}
+    for class, n := range s.errors {
+        byClass[string(class)] = n
+    }
+
+    return s.calls, s.failed, byClass
+}
+
+// String formats the summary for logs, e.g. "12 pages, 1 failed (timeout: 3, parse: 1)"
+func (s *Stats) String() string {
+    calls, failed, errs := s.Summary()
+
+    var classes []string
+    for class := range errs {
+        classes = append(classes, class)
+    }
+    sort.Strings(classes)
+
+    var counts []string
+    for _, class := range classes {
+        counts = append(counts, fmt.Sprintf("%s: %d", class, errs[class]))
+    }
+
+    out := fmt.Sprintf("%d pages, %d failed", calls, failed)
+    if len(counts) > 0 {
+        out += " (" + strings.Join(counts, ", ") + ")"
+    }
+
+    return out
+}
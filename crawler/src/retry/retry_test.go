@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassOf(t *testing.T) {
+    cases := []struct {
+        name string
+        err error
+        want Class
+    }{
+        {"nil", nil, ""},
+        {"classified", Errorf(Parse, "no teams"), Parse},
+        {"wrapped", fmt.Errorf("page: %w", Errorf(Blocked, "captcha")), Blocked},
+        {"kept on wrap", Wrap(Storage, Errorf(Parse, "no teams")), Parse},
+        {"deadline", context.DeadlineExceeded, Timeout},
+        {"chrome", errors.New("page load error net::ERR_CONNECTION_RESET"), Network},
+        {"other", errors.New("boom"), Unknown},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            if got := ClassOf(c.err); got != c.want {
+                t.Errorf("ClassOf(%v) = %q, want %q", c.err, got, c.want)
+            }
+        })
+    }
+}
+
+func TestRetryable(t *testing.T) {
+    cases := map[Class]bool{
+        Timeout: true,
+        Network: true,
+        Storage: true,
+        SelectorNotFound: false,
+        Blocked: false,
+        GeoBlocked: false,
+        Parse: false,
+        Unknown: false,
+    }
+
+    for class, want := range cases {
+        if got := Retryable(class); got != want {
+            t.Errorf("Retryable(%s) = %v, want %v", class, got, want)
+        }
+    }
+}
+
+func TestDo(t *testing.T) {
+    p := Policy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+    cases := []struct {
+        name string
+        errs []error
+        calls int
+        failed bool
+        summary string
+    }{
+        {"ok", nil, 1, false, "1 pages, 0 failed"},
+        {"retried", []error{Errorf(Network, "reset")}, 2, false, "1 pages, 0 failed (network: 1)"},
+        {"out of attempts", []error{Errorf(Timeout, "slow"), Errorf(Network, "reset"), Errorf(Timeout, "slow")}, 3, true, "1 pages, 1 failed (network: 1, timeout: 2)"},
+        {"not retryable", []error{Errorf(Parse, "no teams")}, 1, true, "1 pages, 1 failed (parse: 1)"},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            stats := &Stats{}
+            calls := 0
+
+            err := p.Do(context.Background(), stats, func(ctx context.Context) error {
+                calls++
+                if calls <= len(c.errs) {
+                    return c.errs[calls - 1]
+                }
+
+                return nil
+            })
+
+            if calls != c.calls || (err != nil) != c.failed {
+                t.Errorf("called %d times with %v, want %d calls, failed %v", calls, err, c.calls, c.failed)
+            }
+
+            if got := stats.String(); got != c.summary {
+                t.Errorf("summary %q, want %q", got, c.summary)
+            }
+        })
+    }
+}
+
+// a crawl cancelled during the backoff is not tried again
+func TestDoCancelled(t *testing.T) {
+    ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Millisecond)
+    defer cancel()
+
+    p := Policy{Attempts: 3, Backoff: time.Hour}
+    calls := 0
+
+    err := p.Do(ctx, nil, func(ctx context.Context) error {
+        calls++
+        return Errorf(Network, "reset")
+    })
+
+    if calls != 1 || !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("called %d times with %v, want 1 call and the crawl cancelled", calls, err)
+    }
+}
+
+func TestDelay(t *testing.T) {
+    p := Policy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+    for attempt, limit := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 40: 5 * time.Second} {
+        for i := 0; i < 20; i++ {
+            if d := p.delay(attempt); d <= 0 || d > limit {
+                t.Fatalf("delay(%d) = %s, want up to %s", attempt, d, limit)
+            }
+        }
+    }
+
+    if d := (Policy{}).delay(3); d != 0 {
+        t.Errorf("delay without backoff = %s, want 0", d)
+    }
+}
@@ -0,0 +1,244 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mxshs/crawler/src/db"
+	"mxshs/crawler/src/logs"
+
+	"github.com/robfig/cron"
+)
+
+// Parse checks a schedule: a standard 5 field cron expression
+// ("*/30 * * * *") or a descriptor like "@hourly" and "@every 45m"
+func Parse(spec string) (cron.Schedule, error) {
+    s, err := cron.ParseStandard(spec)
+    if err != nil {
+        return nil, fmt.Errorf("[ERROR] Bad schedule %q: %s", spec, err.Error())
+    }
+
+    return s, nil
+}
+
+// MaxGap is the longest wait between two runs of s in the week after from
+func MaxGap(s cron.Schedule, from time.Time) time.Duration {
+    var gap time.Duration
+
+    prev := s.Next(from)
+    for prev.Sub(from) < 7 * 24 * time.Hour {
+        next := s.Next(prev)
+        if next.IsZero() {
+            break
+        }
+
+        gap = max(gap, next.Sub(prev))
+        prev = next
+    }
+
+    return gap
+}
+
+// Job crawls the start urls of one source on a schedule
+type Job struct {
+    Source string
+    Spec string
+    URLs []string
+}
+
+// CrawlFunc crawls the urls of a source, the error is kept as the outcome
+// of the run
+type CrawlFunc func(ctx context.Context, source string, urls []string) error
+
+// Status of a job as reported by the daemon
+type Status struct {
+    Source string `json:"source"`
+    Schedule string `json:"schedule"`
+    URLs []string `json:"urls"`
+    Running bool `json:"running"`
+    Next time.Time `json:"next"`
+    LastStart time.Time `json:"last_start"`
+    LastFinish time.Time `json:"last_finish"`
+    // done, failed or cancelled, see db.Run
+    LastStatus string `json:"last_status,omitempty"`
+    LastError string `json:"last_error,omitempty"`
+}
+
+// Scheduler runs every job in its own goroutine, so a source never has two
+// crawls at once: when a crawl takes longer than its interval the ticks it
+// overlapped are skipped. Different sources crawl in parallel
+type Scheduler struct {
+    crawl CrawlFunc
+    jobs []job
+
+    mu sync.Mutex
+    status map[string]*Status
+}
+
+type job struct {
+    Job
+    schedule cron.Schedule
+}
+
+func GetScheduler(jobs []Job, crawl CrawlFunc) (*Scheduler, error) {
+    s := &Scheduler{crawl: crawl, status: map[string]*Status{}}
+
+    for _, j := range jobs {
+        schedule, err := Parse(j.Spec)
+        if err != nil {
+            return nil, err
+        }
+
+        s.jobs = append(s.jobs, job{Job: j, schedule: schedule})
+        s.status[j.Source] = &Status{Source: j.Source, Schedule: j.Spec, URLs: j.URLs}
+    }
+
+    return s, nil
+}
+
+// Restore picks up where a previous daemon stopped from the latest stored
+// run of every source: the next crawl is planned from when that run started,
+// a crawl that was missed while the daemon was down runs right away (once)
+func (s *Scheduler) Restore(last map[string]db.Run) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for source, run := range last {
+        st, ok := s.status[source]
+        if !ok {
+            continue
+        }
+
+        st.LastStart = run.StartedAt
+        st.LastFinish = run.FinishedAt
+        st.LastStatus = run.Status
+        st.LastError = run.Error
+    }
+}
+
+// Run starts the jobs and blocks until ctx is done and the crawls that were
+// running at that moment finished
+func (s *Scheduler) Run(ctx context.Context) {
+    var wg sync.WaitGroup
+
+    for _, j := range s.jobs {
+        wg.Add(1)
+
+        go func(j job) {
+            defer wg.Done()
+            s.loop(ctx, j)
+        } (j)
+    }
+
+    wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+    next := s.first(j)
+
+    for {
+        s.update(j.Source, func(st *Status) {
+            st.Next = next
+        })
+
+        logs.Infof("Next %s crawl at %s", j.Source, next.Format(time.RFC3339))
+
+        timer := time.NewTimer(max(time.Until(next), 0))
+
+        select {
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        }
+
+        start := time.Now()
+
+        s.update(j.Source, func(st *Status) {
+            st.Running = true
+            st.LastStart = start
+        })
+
+        err := s.crawl(ctx, j.Source, j.URLs)
+
+        s.update(j.Source, func(st *Status) {
+            st.Running = false
+            st.LastFinish = time.Now()
+            st.LastStatus, st.LastError = outcome(err)
+        })
+
+        if err != nil && ctx.Err() == nil {
+            logs.Errorf("Scheduled %s crawl failed: %s", j.Source, err.Error())
+        }
+
+        if ctx.Err() != nil {
+            return
+        }
+
+        next = j.schedule.Next(start)
+        if now := time.Now(); next.Before(now) {
+            skipped := 0
+            for ; next.Before(now); next = j.schedule.Next(next) {
+                skipped++
+            }
+
+            logs.Infof("%s crawl took %s, skipped %d scheduled runs", j.Source, now.Sub(start).Round(time.Second), skipped)
+        }
+    }
+}
+
+// first is the time of the first crawl of j after the daemon starts
+func (s *Scheduler) first(j job) time.Time {
+    now := time.Now()
+
+    s.mu.Lock()
+    last := s.status[j.Source].LastStart
+    s.mu.Unlock()
+
+    if last.IsZero() {
+        return j.schedule.Next(now)
+    }
+
+    next := j.schedule.Next(last)
+    if next.Before(now) {
+        return now
+    }
+
+    return next
+}
+
+func (s *Scheduler) update(source string, fn func(st *Status)) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    fn(s.status[source])
+}
+
+// Status of every job ordered by source
+func (s *Scheduler) Status() []Status {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    res := make([]Status, 0, len(s.status))
+    for _, st := range s.status {
+        res = append(res, *st)
+    }
+
+    sort.Slice(res, func(i, j int) bool {
+        return res[i].Source < res[j].Source
+    })
+
+    return res
+}
+
+// outcome of a crawl as the status of its run is stored
+func outcome(err error) (string, string) {
+    if err == nil {
+        return db.RunDone, ""
+    }
+
+    return db.RunStatus(err), err.Error()
+}
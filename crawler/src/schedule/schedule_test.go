@@ -0,0 +1,83 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"mxshs/crawler/src/bookmaker"
+	"mxshs/crawler/src/db"
+)
+
+// the scheduler reports a crawl the way its run is closed
+func TestOutcome(t *testing.T) {
+    cases := []struct {
+        name string
+        err error
+        status string
+    }{
+        {"done", nil, db.RunDone},
+        {"shutdown", fmt.Errorf("crawl: %w", context.Canceled), db.RunCancelled},
+        {"run timeout", fmt.Errorf("crawl: %w", context.DeadlineExceeded), db.RunCancelled},
+        {"failed", errors.New("no parser"), db.RunFailed},
+    }
+
+    store := db.GetMemoryDB()
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            status, msg := outcome(c.err)
+            if status != c.status {
+                t.Errorf("status %s, want %s", status, c.status)
+            }
+
+            if c.err != nil && msg != c.err.Error() {
+                t.Errorf("error %q, want %q", msg, c.err.Error())
+            }
+
+            id, err := store.StartRun(context.Background(), bookmaker.Bookmaker{Code: "leon"}, "https://leon.ru/")
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            err = store.FinishRun(context.Background(), id, db.RunStats{}, c.err)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            runs := store.Runs()
+            if run := runs[len(runs) - 1]; run.Status != status {
+                t.Errorf("run closed as %s, scheduler reports %s", run.Status, status)
+            }
+        })
+    }
+}
+
+func TestMaxGap(t *testing.T) {
+    from := time.Date(2026, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+    cases := []struct {
+        spec string
+        want time.Duration
+    }{
+        {"*/30 * * * *", 30 * time.Minute},
+        {"@every 2m", 2 * time.Minute},
+        {"@hourly", time.Hour},
+        {"0 9,21 * * *", 12 * time.Hour},
+        {"0 8,10 * * *", 22 * time.Hour},
+        {"0 12 * * 1", 7 * 24 * time.Hour},
+    }
+
+    for _, c := range cases {
+        s, err := Parse(c.spec)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if got := MaxGap(s, from); got != c.want {
+            t.Errorf("MaxGap(%q) = %s, want %s", c.spec, got, c.want)
+        }
+    }
+}
@@ -0,0 +1,195 @@
+package teams
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mxshs/crawler/src/textsim"
+)
+
+// Team is a canonical team name with the approved spellings sites use for it
+type Team struct {
+    Name string
+    Aliases []string
+}
+
+// Suggestion is a team name the registry does not know with the closest
+// canonical team, waiting to be reviewed
+type Suggestion struct {
+    Alias string
+    Team string
+    Score float64
+    Source string
+    SeenAt time.Time
+}
+
+// suggestions below this score are not worth showing
+const minSuggestScore = 0.6
+
+var translit = map[rune]string{
+    'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+    'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+    'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+    'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+    'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Translit replaces cyrillic letters with their latin transcription
+func Translit(s string) string {
+    var b strings.Builder
+
+    for _, r := range strings.ToLower(s) {
+        if t, ok := translit[r]; ok {
+            b.WriteString(t)
+        } else {
+            b.WriteRune(r)
+        }
+    }
+
+    return b.String()
+}
+
+// Key is the form names are looked up by: transliterated and normalized
+func Key(name string) string {
+    return textsim.Normalize(Translit(name))
+}
+
+// Registry maps team names as scraped to canonical ones. Names it does not
+// know are kept as is and remembered with a suggestion for review
+type Registry struct {
+    mu sync.Mutex
+    canonical map[string]string
+    unknown map[string]Suggestion
+}
+
+func GetRegistry(list []Team) *Registry {
+    r := &Registry{
+        canonical: map[string]string{},
+        unknown: map[string]Suggestion{},
+    }
+
+    for _, t := range list {
+        r.Add(t)
+    }
+
+    return r
+}
+
+func (r *Registry) Add(t Team) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.canonical[Key(t.Name)] = t.Name
+
+    for _, a := range t.Aliases {
+        r.canonical[Key(a)] = t.Name
+    }
+}
+
+// Resolve returns the canonical name for a scraped one
+func (r *Registry) Resolve(name, source string) string {
+    name = strings.TrimSpace(name)
+    if len(name) == 0 {
+        return name
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if c, ok := r.canonical[Key(name)]; ok {
+        return c
+    }
+
+    if _, ok := r.unknown[name]; !ok {
+        s, _ := r.suggest(name)
+        s.Source = source
+        s.SeenAt = time.Now().UTC()
+        r.unknown[name] = s
+    }
+
+    return name
+}
+
+// Suggest finds the canonical team closest to name
+func (r *Registry) Suggest(name string) (Suggestion, bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.suggest(name)
+}
+
+func (r *Registry) suggest(name string) (Suggestion, bool) {
+    best := Suggestion{Alias: name}
+    key := Key(name)
+
+    for k, team := range r.canonical {
+        score := similarity(key, k)
+        if score > best.Score || score == best.Score && team < best.Team {
+            best.Team = team
+            best.Score = score
+        }
+    }
+
+    if best.Score < minSuggestScore {
+        best.Team = ""
+        best.Score = 0
+        return best, false
+    }
+
+    return best, true
+}
+
+// Unknown returns names resolved since the registry was created that matched
+// no team, sorted by alias
+func (r *Registry) Unknown() []Suggestion {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    var res []Suggestion
+
+    for _, s := range r.unknown {
+        res = append(res, s)
+    }
+
+    sort.Slice(res, func(i, j int) bool { return res[i].Alias < res[j].Alias })
+
+    return res
+}
+
+// similarity of two keys. Names where one is the other with words dropped
+// ("spirit" and "team spirit") score high even though edit distance is large
+func similarity(a, b string) float64 {
+    score := textsim.Similarity(a, b)
+
+    ta, tb := strings.Fields(a), strings.Fields(b)
+    if len(ta) > len(tb) {
+        ta, tb = tb, ta
+    }
+
+    if len(ta) > 0 && containsAll(tb, ta) {
+        score = max(score, 0.9)
+    }
+
+    return score
+}
+
+func containsAll(set, items []string) bool {
+    for _, i := range items {
+        found := false
+
+        for _, s := range set {
+            if s == i {
+                found = true
+                break
+            }
+        }
+
+        if !found {
+            return false
+        }
+    }
+
+    return true
+}
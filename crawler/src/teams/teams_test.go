@@ -0,0 +1,101 @@
+package teams
+
+import (
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+    cases := []struct {
+        name string
+        want string
+    }{
+        {"Team Spirit", "team spirit"},
+        {"  Team-Spirit ", "team spirit"},
+        {"Тим Спирит", "tim spirit"},
+        {"Натус Винсере", "natus vinsere"},
+        {"Virtus.pro", "virtus pro"},
+    }
+
+    for _, c := range cases {
+        if got := Key(c.name); got != c.want {
+            t.Errorf("Key(%q) = %q, want %q", c.name, got, c.want)
+        }
+    }
+}
+
+func TestResolve(t *testing.T) {
+    r := GetRegistry([]Team{
+        {Name: "Team Spirit", Aliases: []string{"Тим Спирит"}},
+        {Name: "OG"},
+    })
+
+    cases := []struct {
+        name string
+        want string
+    }{
+        {"Team Spirit", "Team Spirit"},
+        {" team spirit ", "Team Spirit"},
+        {"Team-Spirit", "Team Spirit"},
+        {"Тим Спирит", "Team Spirit"},
+        {"og", "OG"},
+        // unknown names are kept as scraped
+        {"Spirit", "Spirit"},
+        {" Tundra Esports ", "Tundra Esports"},
+        {"", ""},
+    }
+
+    for _, c := range cases {
+        if got := r.Resolve(c.name, "leon"); got != c.want {
+            t.Errorf("Resolve(%q) = %q, want %q", c.name, got, c.want)
+        }
+    }
+
+    unknown := r.Unknown()
+    if len(unknown) != 2 || unknown[0].Alias != "Spirit" || unknown[1].Alias != "Tundra Esports" {
+        t.Fatalf("Unknown() = %+v, want Spirit and Tundra Esports", unknown)
+    }
+
+    // a name that is a known one with words dropped is suggested for it
+    if s := unknown[0]; s.Team != "Team Spirit" || s.Score < minSuggestScore || s.Source != "leon" {
+        t.Errorf("suggestion for Spirit = %+v, want Team Spirit from leon", s)
+    }
+
+    if s := unknown[1]; len(s.Team) > 0 {
+        t.Errorf("suggestion for Tundra Esports = %+v, want none", s)
+    }
+}
+
+func TestAdd(t *testing.T) {
+    r := GetRegistry(nil)
+
+    if got := r.Resolve("Spirit", "leon"); got != "Spirit" {
+        t.Fatalf("Resolve(Spirit) = %q before the alias was added", got)
+    }
+
+    r.Add(Team{Name: "Team Spirit", Aliases: []string{"Spirit"}})
+
+    if got := r.Resolve("Spirit", "leon"); got != "Team Spirit" {
+        t.Errorf("Resolve(Spirit) = %q, want Team Spirit", got)
+    }
+}
+
+func TestSuggest(t *testing.T) {
+    r := GetRegistry([]Team{{Name: "Gaimin Gladiators"}, {Name: "Team Liquid"}})
+
+    cases := []struct {
+        name string
+        team string
+        ok bool
+    }{
+        {"Gaimin Gladiator", "Gaimin Gladiators", true},
+        {"Liquid", "Team Liquid", true},
+        {"Xtreme Gaming", "", false},
+    }
+
+    for _, c := range cases {
+        s, ok := r.Suggest(c.name)
+        if ok != c.ok || s.Team != c.team {
+            t.Errorf("Suggest(%q) = %+v %v, want %q %v", c.name, s, ok, c.team, c.ok)
+        }
+    }
+}
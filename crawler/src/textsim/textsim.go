@@ -0,0 +1,96 @@
+package textsim
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize lowercases the string, replaces punctuation with spaces and
+// collapses whitespace
+func Normalize(s string) string {
+    s = strings.ToLower(s)
+
+    s = strings.Map(func(r rune) rune {
+        if unicode.IsLetter(r) || unicode.IsDigit(r) {
+            return r
+        }
+        return ' '
+    }, s)
+
+    return strings.Join(strings.Fields(s), " ")
+}
+
+func Tokens(s string) []string {
+    return strings.Fields(Normalize(s))
+}
+
+// Levenshtein distance between a and b counted in runes
+func Levenshtein(a, b string) int {
+    ra, rb := []rune(a), []rune(b)
+
+    prev := make([]int, len(rb) + 1)
+    cur := make([]int, len(rb) + 1)
+
+    for j := range prev {
+        prev[j] = j
+    }
+
+    for i := 1; i <= len(ra); i++ {
+        cur[0] = i
+
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i - 1] == rb[j - 1] {
+                cost = 0
+            }
+
+            cur[j] = min(prev[j] + 1, cur[j - 1] + 1, prev[j - 1] + cost)
+        }
+
+        prev, cur = cur, prev
+    }
+
+    return prev[len(rb)]
+}
+
+// Similarity is 1 - normalized edit distance, 1 for equal strings
+func Similarity(a, b string) float64 {
+    if a == b {
+        return 1
+    }
+
+    l := max(len([]rune(a)), len([]rune(b)))
+    if l == 0 {
+        return 1
+    }
+
+    return 1 - float64(Levenshtein(a, b)) / float64(l)
+}
+
+// Jaccard similarity of the token sets of a and b
+func Jaccard(a, b string) float64 {
+    ta, tb := Tokens(a), Tokens(b)
+    if len(ta) == 0 && len(tb) == 0 {
+        return 1
+    }
+
+    set := map[string]int{}
+
+    for _, t := range ta {
+        set[t] |= 1
+    }
+
+    for _, t := range tb {
+        set[t] |= 2
+    }
+
+    common := 0
+
+    for _, v := range set {
+        if v == 3 {
+            common += 1
+        }
+    }
+
+    return float64(common) / float64(len(set))
+}